@@ -0,0 +1,72 @@
+/*
+Copyright © 2023 Kovalev Pavel kovalev5690@gmail.com
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Pavel7004/goMimeMagic/pkg/magic"
+)
+
+var detectCmd = &cobra.Command{
+	Use:   "detect <path>...",
+	Short: "Print the MIME type of files",
+	Long: `Detect the MIME type of every given file using the magic database.
+
+Example: magic detect image.png archive.zip
+This will print one line per file, like "image.png: image/png".
+Files no signature matches are reported as "unknown".`,
+	Args: cobra.MinimumNArgs(1),
+	Run:  detect,
+}
+
+func init() {
+	rootCmd.AddCommand(detectCmd)
+}
+
+func detect(cmd *cobra.Command, args []string) {
+	secs, err := readDatabase()
+	cobra.CheckErr(err)
+
+	db := magic.NewDatabase(secs)
+
+	failed := 0
+	for _, path := range args {
+		filetype, err := db.DetectFile(path)
+		if errors.Is(err, magic.ErrNoMatch) {
+			filetype = "unknown"
+		} else if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", path, err)
+			failed++
+			continue
+		}
+
+		fmt.Printf("%s: %s\n", path, filetype)
+	}
+
+	if failed > 0 {
+		cobra.CheckErr(fmt.Errorf("failed to read %d of %d files", failed, len(args)))
+	}
+}
@@ -0,0 +1,74 @@
+/*
+Copyright © 2023 Kovalev Pavel kovalev5690@gmail.com
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/package cmd
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"os"
+
+	"github.com/Pavel7004/goMimeMagic/pkg/domain"
+)
+
+type jsonSection struct {
+	Filetype string        `json:"filetype"`
+	Priority uint          `json:"priority"`
+	Contents []jsonContent `json:"contents"`
+}
+
+type jsonContent struct {
+	Indent    uint   `json:"indent"`
+	Offset    uint   `json:"offset"`
+	OffsetEnd uint   `json:"offset_end,omitempty"`
+	Value     string `json:"value"`
+	Mask      string `json:"mask"`
+	Range     uint   `json:"range"`
+	WordSize  uint   `json:"word_size"`
+}
+
+// printJSON writes secs as a JSON array with Value and Mask hex-encoded.
+// Only the flat content list is written; nesting is given by the indents.
+func printJSON(secs []*domain.Section) error {
+	out := make([]jsonSection, 0, len(secs))
+	for _, sec := range secs {
+		js := jsonSection{
+			Filetype: sec.Filetype,
+			Priority: sec.Priority,
+			Contents: make([]jsonContent, 0, len(sec.Contents)),
+		}
+		for _, con := range sec.Contents {
+			js.Contents = append(js.Contents, jsonContent{
+				Indent:    con.Indent,
+				Offset:    con.Offset,
+				OffsetEnd: con.OffsetEnd,
+				Value:     hex.EncodeToString(con.Value),
+				Mask:      hex.EncodeToString(con.Mask),
+				Range:     con.RangeLength,
+				WordSize:  con.WordSize,
+			})
+		}
+		out = append(out, js)
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
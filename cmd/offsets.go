@@ -0,0 +1,86 @@
+/*
+Copyright © 2023 Kovalev Pavel kovalev5690@gmail.com
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/package cmd
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Pavel7004/goMimeMagic/pkg/magic"
+)
+
+var offsetsByType bool
+
+var offsetsCmd = &cobra.Command{
+	Use:   "offsets",
+	Short: "Print byte offsets examined by signatures",
+	Long: `Print the range of byte offsets signatures are anchored at.
+
+Example: magic offsets --by-type
+This will print the offset range for every type, so types that need
+only the file header can be told apart from deep-offset ones.`,
+	Args: cobra.NoArgs,
+	Run:  printOffsets,
+}
+
+func init() {
+	offsetsCmd.Flags().BoolVarP(&offsetsByType, "by-type", "t", false, "Print offsets for every type")
+	rootCmd.AddCommand(offsetsCmd)
+}
+
+func printOffsets(cmd *cobra.Command, args []string) {
+	secs, err := readDatabase()
+	cobra.CheckErr(err)
+
+	bounds := magic.TypeOffsetBounds(secs)
+
+	if offsetsByType {
+		types := make([]string, 0, len(bounds))
+		for filetype := range bounds {
+			types = append(types, filetype)
+		}
+		sort.Strings(types)
+
+		for _, filetype := range types {
+			fmt.Printf("%s: %d-%d\n", filetype, bounds[filetype][0], bounds[filetype][1])
+		}
+		return
+	}
+
+	if len(bounds) == 0 {
+		return
+	}
+
+	first := true
+	var total [2]uint
+	for _, b := range bounds {
+		if first || b[0] < total[0] {
+			total[0] = b[0]
+		}
+		if first || b[1] > total[1] {
+			total[1] = b[1]
+		}
+		first = false
+	}
+	fmt.Printf("Offsets: %d-%d\n", total[0], total[1])
+}
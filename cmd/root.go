@@ -25,17 +25,27 @@ import (
 	"io"
 	"log"
 	"os"
+	"path"
+	"sort"
 	"strings"
 
 	"github.com/spf13/cobra"
 
+	"github.com/Pavel7004/goMimeMagic/pkg/domain"
 	"github.com/Pavel7004/goMimeMagic/pkg/magic"
 )
 
 var (
+	magicFile       string
+	cachePath       string
 	debug           bool
 	showMask        bool
 	showStringValue bool
+	outputFormat    string
+	filterPattern   string
+	minPriority     uint
+	sortOrder       string
+	showCount       bool
 )
 
 var rootCmd = &cobra.Command{
@@ -46,7 +56,8 @@ to get binary signatures for filetypes.
 
 Example: magic
 This will print all types and their signatures.`,
-	Run: listAll,
+	PersistentPreRun: setupLogging,
+	Run:              listAll,
 }
 
 func Execute() {
@@ -57,27 +68,98 @@ func Execute() {
 }
 
 func init() {
-	rootCmd.Flags().BoolVarP(&debug, "debug", "d", false, "Turn on debug info")
+	rootCmd.PersistentFlags().StringVarP(&magicFile, "file", "f", magic.DefaultPath, "Path to the magic database")
+	rootCmd.PersistentFlags().StringVar(&cachePath, "cache", "", "Path to a parsed database cache, rebuilt when the magic file is newer or another one")
+	rootCmd.PersistentFlags().BoolVarP(&debug, "debug", "d", false, "Turn on debug info")
 	rootCmd.Flags().BoolVarP(&showMask, "with-mask", "m", false, "Print mask")
 	rootCmd.Flags().BoolVarP(&showStringValue, "value-as-string", "s", false, "Print value as sequence of characters")
+	rootCmd.Flags().StringVarP(&outputFormat, "format", "o", "text", "Output format: text or json")
+	rootCmd.Flags().UintVar(&minPriority, "min-priority", 0, "Print only sections with at least this priority")
+	rootCmd.Flags().StringVar(&filterPattern, "filter", "", "Print only filetypes matching the pattern, e.g. 'image/*'")
+	rootCmd.Flags().BoolVar(&showCount, "count", false, "Print statistics about the database instead of the sections")
+	rootCmd.Flags().StringVar(&sortOrder, "sort", "", "Sort sections by priority or name instead of file order")
 }
 
-func listAll(cmd *cobra.Command, args []string) {
+func setupLogging(cmd *cobra.Command, args []string) {
 	if !debug {
 		log.SetFlags(0)
 		log.SetOutput(io.Discard)
 	} else {
 		log.SetFlags(log.Lshortfile)
 	}
+}
 
-	r := magic.NewMagicReader()
+func readDatabase() ([]*domain.Section, error) {
+	if cachePath != "" {
+		fresh, err := magic.CacheIsFresh(cachePath, magicFile)
+		if err != nil {
+			log.Printf("Failed to check cache freshness. err = %v", err)
+		}
+		if fresh {
+			secs, err := magic.LoadCache(cachePath)
+			if err == nil {
+				return secs, nil
+			}
+			log.Printf("Failed to load cache. err = %v", err)
+		}
+	}
 
-	cobra.CheckErr(r.Open())
-	defer cobra.CheckErr(r.Close())
+	var opts []magic.Option
+	if debug {
+		opts = append(opts, magic.WithLogger(log.Default()))
+	}
+
+	r := magic.NewMagicReaderWithPath(magicFile, opts...)
+
+	if err := r.Open(); err != nil {
+		return nil, err
+	}
+	defer r.Close()
 
 	secs, err := r.ReadSections()
+	if err != nil {
+		return nil, err
+	}
+
+	if cachePath != "" {
+		if err := magic.SaveCache(secs, cachePath, magicFile); err != nil {
+			log.Printf("Failed to save cache. err = %v", err)
+		}
+	}
+
+	return secs, nil
+}
+
+func listAll(cmd *cobra.Command, args []string) {
+	if outputFormat != "text" && outputFormat != "json" {
+		cobra.CheckErr(fmt.Errorf("unknown format %q, expected text or json", outputFormat))
+	}
+
+	if sortOrder != "" && sortOrder != "priority" && sortOrder != "name" {
+		cobra.CheckErr(fmt.Errorf("unknown sort order %q, expected priority or name", sortOrder))
+	}
+
+	if filterPattern != "" {
+		_, err := path.Match(filterPattern, "")
+		cobra.CheckErr(err)
+	}
+
+	secs, err := readDatabase()
 	cobra.CheckErr(err)
 
+	secs = filterSections(secs)
+	sortSections(secs)
+
+	if showCount {
+		printStats(secs)
+		return
+	}
+
+	if outputFormat == "json" {
+		cobra.CheckErr(printJSON(secs))
+		return
+	}
+
 	for _, sec := range secs {
 		fmt.Printf("Filetype: %s\n", sec.Filetype)
 		fmt.Printf("Priority: %d\n", sec.Priority)
@@ -112,3 +194,47 @@ func listAll(cmd *cobra.Command, args []string) {
 		fmt.Printf(" ------- \n")
 	}
 }
+
+func filterSections(secs []*domain.Section) []*domain.Section {
+	filtered := make([]*domain.Section, 0, len(secs))
+	for _, sec := range secs {
+		if sec.Priority < minPriority {
+			continue
+		}
+		if filterPattern != "" && !matchFiletype(filterPattern, sec.Filetype) {
+			continue
+		}
+		filtered = append(filtered, sec)
+	}
+	return filtered
+}
+
+// sortSections orders secs by descending priority or by filetype. The sort
+// is stable, so ties keep their order in the file.
+func sortSections(secs []*domain.Section) {
+	switch sortOrder {
+	case "priority":
+		sort.SliceStable(secs, func(i, j int) bool {
+			return secs[i].Priority > secs[j].Priority
+		})
+	case "name":
+		sort.SliceStable(secs, func(i, j int) bool {
+			return secs[i].Filetype < secs[j].Filetype
+		})
+	}
+}
+
+// matchFiletype matches filetype against a path.Match pattern. A pattern
+// without '/' is also tried on the subtype alone, since '*' doesn't match
+// the slash, so that '*pdf*' finds application/pdf.
+func matchFiletype(pattern, filetype string) bool {
+	if ok, _ := path.Match(pattern, filetype); ok {
+		return true
+	}
+	if strings.Contains(pattern, "/") {
+		return false
+	}
+	_, subtype, _ := strings.Cut(filetype, "/")
+	ok, _ := path.Match(pattern, subtype)
+	return ok
+}
@@ -0,0 +1,121 @@
+/*
+Copyright © 2023 Kovalev Pavel kovalev5690@gmail.com
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/package cmd
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Pavel7004/goMimeMagic/pkg/domain"
+)
+
+var sniffTableLang string
+
+var sniffTableCmd = &cobra.Command{
+	Use:   "sniff-table",
+	Short: "Generate a sniff table for embedding in other programs",
+	Long: `Generate a compact table of signatures from the magic database.
+
+Only top-level rules without nested rules and without byte swapping are
+emitted, so every entry can be checked on its own: a type matches when
+the masked bytes equal the value at any offset in [offset, offset+range).
+
+Example: magic sniff-table --lang go
+This will print a Go source snippet declaring the table.`,
+	Args: cobra.NoArgs,
+	Run:  sniffTable,
+}
+
+type sniffRule struct {
+	Type     string `json:"type"`
+	Priority uint   `json:"priority"`
+	Offset   uint   `json:"offset"`
+	Range    uint   `json:"range"`
+	Value    string `json:"value"`
+	Mask     string `json:"mask"`
+}
+
+func init() {
+	sniffTableCmd.Flags().StringVarP(&sniffTableLang, "lang", "l", "json", "Output language: go or json")
+	rootCmd.AddCommand(sniffTableCmd)
+}
+
+func sniffTable(cmd *cobra.Command, args []string) {
+	if sniffTableLang != "go" && sniffTableLang != "json" {
+		cobra.CheckErr(fmt.Errorf("unknown language %q, expected go or json", sniffTableLang))
+	}
+
+	secs, err := readDatabase()
+	cobra.CheckErr(err)
+
+	rules := sniffRules(secs)
+
+	if sniffTableLang == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		cobra.CheckErr(enc.Encode(rules))
+		return
+	}
+
+	fmt.Println("var sniffRules = []struct {")
+	fmt.Println("\tType     string")
+	fmt.Println("\tPriority uint")
+	fmt.Println("\tOffset   uint")
+	fmt.Println("\tRange    uint")
+	fmt.Println("\tValue    []byte")
+	fmt.Println("\tMask     []byte")
+	fmt.Println("}{")
+	for _, rule := range rules {
+		value, _ := hex.DecodeString(rule.Value)
+		mask, _ := hex.DecodeString(rule.Mask)
+		fmt.Printf("\t{%q, %d, %d, %d, []byte(%q), []byte(%q)},\n",
+			rule.Type, rule.Priority, rule.Offset, rule.Range, value, mask)
+	}
+	fmt.Println("}")
+}
+
+func sniffRules(secs []*domain.Section) []sniffRule {
+	rules := make([]sniffRule, 0, len(secs))
+	for _, sec := range secs {
+		for i, con := range sec.Contents {
+			if con.Indent != 0 || con.WordSize > 1 {
+				continue
+			}
+			if i+1 < len(sec.Contents) && sec.Contents[i+1].Indent > 0 {
+				continue
+			}
+
+			rules = append(rules, sniffRule{
+				Type:     sec.Filetype,
+				Priority: sec.Priority,
+				Offset:   con.Offset,
+				Range:    con.Span(),
+				Value:    hex.EncodeToString(con.Value),
+				Mask:     hex.EncodeToString(con.Mask),
+			})
+		}
+	}
+	return rules
+}
@@ -0,0 +1,87 @@
+/*
+Copyright © 2023 Kovalev Pavel kovalev5690@gmail.com
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/package cmd
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/Pavel7004/goMimeMagic/pkg/domain"
+)
+
+func TestSniffRulesJSONRoundTrip(t *testing.T) {
+	png := &domain.Content{Value: []byte("\x89PNG"), Mask: []byte{0xff, 0xff, 0xff, 0xff}}
+	text := &domain.Content{Offset: 4, OffsetEnd: 8, Value: []byte("<?xml"), Mask: []byte{0xff, 0xdf, 0xdf, 0xdf, 0xff}, RangeLength: 2}
+	secs := []*domain.Section{
+		{Filetype: "image/png", Priority: 50, Contents: []*domain.Content{png}},
+		{Filetype: "text/xml", Priority: 40, Contents: []*domain.Content{text}},
+		{Filetype: "x/swapped", Priority: 30, Contents: []*domain.Content{
+			{Value: []byte("ABCD"), Mask: []byte{0xff, 0xff, 0xff, 0xff}, WordSize: 4},
+		}},
+		{Filetype: "x/nested", Priority: 20, Contents: []*domain.Content{
+			{Value: []byte("PK"), Mask: []byte{0xff, 0xff}},
+			{Indent: 1, Offset: 30, Value: []byte("mimetype"), Mask: bytes.Repeat([]byte{0xff}, 8)},
+		}},
+	}
+
+	rules := sniffRules(secs)
+	out, err := json.Marshal(rules)
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	var decoded []sniffRule
+	if err := json.Unmarshal(out, &decoded); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	if !reflect.DeepEqual(decoded, rules) {
+		t.Errorf("decoded rules = %+v, want %+v", decoded, rules)
+	}
+
+	// Only the stand-alone rules without byte swapping are emitted, and
+	// each decodes back into a rule trying the same bytes at the same
+	// offsets.
+	want := []*domain.Content{png, text}
+	if len(decoded) != len(want) {
+		t.Fatalf("decoded %d rules, want %d", len(decoded), len(want))
+	}
+	for i, rule := range decoded {
+		value, err := hex.DecodeString(rule.Value)
+		if err != nil {
+			t.Fatalf("rule %d value: %v", i, err)
+		}
+		mask, err := hex.DecodeString(rule.Mask)
+		if err != nil {
+			t.Fatalf("rule %d mask: %v", i, err)
+		}
+
+		con := &domain.Content{Offset: rule.Offset, RangeLength: rule.Range, Value: value, Mask: mask}
+		if con.Offset != want[i].Offset || con.Span() != want[i].Span() ||
+			!bytes.Equal(con.Value, want[i].Value) || !bytes.Equal(con.Mask, want[i].Mask) {
+			t.Errorf("rule %d = %v, want %v", i, con, want[i])
+		}
+		if rule.Type != secs[i].Filetype || rule.Priority != secs[i].Priority {
+			t.Errorf("rule %d type = %s with priority %d, want %s", i, rule.Type, rule.Priority, secs[i])
+		}
+	}
+}
@@ -0,0 +1,67 @@
+/*
+Copyright © 2023 Kovalev Pavel kovalev5690@gmail.com
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/package cmd
+
+import (
+	"fmt"
+
+	"github.com/Pavel7004/goMimeMagic/pkg/domain"
+)
+
+// priorityBucket is the width of the priority histogram buckets.
+const priorityBucket = 10
+
+func printStats(secs []*domain.Section) {
+	filetypes := make(map[string]struct{}, len(secs))
+	buckets := make(map[uint]int)
+	multi := 0
+
+	var minPrio, maxPrio uint
+	for i, sec := range secs {
+		filetypes[sec.Filetype] = struct{}{}
+		buckets[sec.Priority/priorityBucket]++
+		if len(sec.Contents) > 1 {
+			multi++
+		}
+
+		if i == 0 || sec.Priority < minPrio {
+			minPrio = sec.Priority
+		}
+		if i == 0 || sec.Priority > maxPrio {
+			maxPrio = sec.Priority
+		}
+	}
+
+	fmt.Printf("Sections: %d\n", len(secs))
+	fmt.Printf("Filetypes: %d\n", len(filetypes))
+	fmt.Printf("Multi-content sections: %d\n", multi)
+	if len(secs) == 0 {
+		return
+	}
+
+	fmt.Printf("Priority: min %d, max %d\n", minPrio, maxPrio)
+	for b := minPrio / priorityBucket; b <= maxPrio/priorityBucket; b++ {
+		if buckets[b] == 0 {
+			continue
+		}
+		fmt.Printf("  %3d-%-3d %d\n", b*priorityBucket, b*priorityBucket+priorityBucket-1, buckets[b])
+	}
+}
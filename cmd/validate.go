@@ -0,0 +1,72 @@
+/*
+Copyright © 2023 Kovalev Pavel kovalev5690@gmail.com
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Pavel7004/goMimeMagic/pkg/magic"
+)
+
+var validateStrict bool
+
+var validateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Check that the magic database is well formed",
+	Long: `Check that the magic database can be parsed.
+
+Example: magic validate --strict
+This will check the whole file against the shared-mime-info grammar and
+print every violation with its byte offset.`,
+	Args: cobra.NoArgs,
+	Run:  validate,
+}
+
+func init() {
+	validateCmd.Flags().BoolVar(&validateStrict, "strict", false, "Enforce the full magic grammar")
+	rootCmd.AddCommand(validateCmd)
+}
+
+func validate(cmd *cobra.Command, args []string) {
+	if !validateStrict {
+		secs, err := readDatabase()
+		cobra.CheckErr(err)
+
+		fmt.Printf("OK: %d sections\n", len(secs))
+		return
+	}
+
+	data, err := os.ReadFile(magicFile)
+	cobra.CheckErr(err)
+
+	errs := magic.ValidateStrict(data)
+	for _, e := range errs {
+		fmt.Println(e)
+	}
+	if len(errs) > 0 {
+		cobra.CheckErr(fmt.Errorf("found %d grammar violations", len(errs)))
+	}
+
+	fmt.Println("OK")
+}
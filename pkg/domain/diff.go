@@ -0,0 +1,128 @@
+/*
+Copyright © 2023 Kovalev Pavel kovalev5690@gmail.com
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/package domain
+
+import (
+	"bytes"
+	"sort"
+)
+
+type ChangeKind int
+
+const (
+	Added ChangeKind = iota
+	Removed
+	Modified
+)
+
+func (k ChangeKind) String() string {
+	switch k {
+	case Added:
+		return "added"
+	case Removed:
+		return "removed"
+	case Modified:
+		return "modified"
+	}
+	return "unknown"
+}
+
+type Change struct {
+	Filetype string
+	Kind     ChangeKind
+}
+
+// Equal reports whether both sections have the same filetype, priority and
+// contents, compared field by field in order. Tree and Children are built
+// from the contents and are not compared separately.
+func (s *Section) Equal(other *Section) bool {
+	if s == nil || other == nil {
+		return s == other
+	}
+	if s.Filetype != other.Filetype || s.Priority != other.Priority || len(s.Contents) != len(other.Contents) {
+		return false
+	}
+	for i, con := range s.Contents {
+		if !con.Equal(other.Contents[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// Equal reports whether both contents describe the same rule and come from
+// the same source bytes.
+func (c *Content) Equal(other *Content) bool {
+	if c == nil || other == nil {
+		return c == other
+	}
+	return c.sameRule(other) && bytes.Equal(c.Raw, other.Raw)
+}
+
+// DiffSections compares two databases by filetype and returns the changes
+// from a to b sorted by filetype. A filetype is modified when its sections,
+// taken in order, are not all Equal.
+func DiffSections(a, b []*Section) []Change {
+	before := groupByFiletype(a)
+	after := groupByFiletype(b)
+
+	var changes []Change
+	for filetype, old := range before {
+		cur, ok := after[filetype]
+		switch {
+		case !ok:
+			changes = append(changes, Change{Filetype: filetype, Kind: Removed})
+		case !equalSections(old, cur):
+			changes = append(changes, Change{Filetype: filetype, Kind: Modified})
+		}
+	}
+	for filetype := range after {
+		if _, ok := before[filetype]; !ok {
+			changes = append(changes, Change{Filetype: filetype, Kind: Added})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Filetype < changes[j].Filetype
+	})
+
+	return changes
+}
+
+func groupByFiletype(secs []*Section) map[string][]*Section {
+	groups := make(map[string][]*Section, len(secs))
+	for _, sec := range secs {
+		groups[sec.Filetype] = append(groups[sec.Filetype], sec)
+	}
+	return groups
+}
+
+func equalSections(a, b []*Section) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !a[i].Equal(b[i]) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,79 @@
+/*
+Copyright © 2023 Kovalev Pavel kovalev5690@gmail.com
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/package domain
+
+import "bytes"
+
+// Matches reports whether the value of c, masked and byte-swapped as the
+// spec requires, is found in data at any offset of its span. Nested rules
+// in Children are not checked. A value that doesn't fit into data at an
+// offset doesn't match there, so short data never causes a panic.
+func (c *Content) Matches(data []byte) bool {
+	span := c.Span()
+	size := uint(len(c.Value))
+
+	for i := uint(0); i < span; i++ {
+		offset := c.Offset + i
+		// offset < c.Offset catches wrapping around on 32-bit platforms.
+		if offset < c.Offset || offset > uint(len(data)) || size > uint(len(data))-offset {
+			return false
+		}
+		if c.matchAt(data[offset : offset+size]) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Content) matchAt(window []byte) bool {
+	if len(c.Mask) == 0 && !c.swapsWords() {
+		return bytes.Equal(window, c.Value)
+	}
+
+	for i := range window {
+		value, mask := c.WordByte(i)
+		if window[i]&mask != value&mask {
+			return false
+		}
+	}
+	return true
+}
+
+// WordByte returns the value and mask bytes compared with data byte i at
+// the matched offset. On little-endian hosts the value and mask are
+// byte-swapped in groups of WordSize, which the spec requires.
+func (c *Content) WordByte(i int) (byte, byte) {
+	if c.swapsWords() {
+		ws := int(c.WordSize)
+		i += ws - 1 - 2*(i%ws)
+	}
+
+	mask := byte(0xff)
+	if i < len(c.Mask) {
+		mask = c.Mask[i]
+	}
+	return c.Value[i], mask
+}
+
+func (c *Content) swapsWords() bool {
+	ws := int(c.WordSize)
+	return littleEndian && ws > 1 && len(c.Value)%ws == 0
+}
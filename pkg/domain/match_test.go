@@ -0,0 +1,91 @@
+/*
+Copyright © 2023 Kovalev Pavel kovalev5690@gmail.com
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/package domain
+
+import "testing"
+
+func TestMatchesWordSize(t *testing.T) {
+	// The value is stored big-endian. On little-endian hosts each 4-byte
+	// word is swapped before it is compared with the data.
+	value := []byte{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08}
+	host := []byte{0x04, 0x03, 0x02, 0x01, 0x08, 0x07, 0x06, 0x05}
+	if !littleEndian {
+		host = value
+	}
+
+	// The mask ignores value byte 3, which lands on data byte 0 on
+	// little-endian hosts, while value byte 0 lands on data byte 3.
+	mask := []byte{0xff, 0xff, 0xff, 0x00, 0xff, 0xff, 0xff, 0xff}
+	masked, unmasked := 0, 3
+	if !littleEndian {
+		masked, unmasked = 3, 0
+	}
+
+	tests := []struct {
+		name string
+		con  Content
+		data []byte
+		want bool
+	}{
+		{
+			name: "host order",
+			con:  Content{Value: value, WordSize: 4},
+			data: host,
+			want: true,
+		},
+		{
+			name: "big-endian data",
+			con:  Content{Value: value, WordSize: 4},
+			data: value,
+			want: !littleEndian,
+		},
+		{
+			name: "masked byte differs",
+			con:  Content{Value: value, Mask: mask, WordSize: 4},
+			data: flip(host, masked),
+			want: true,
+		},
+		{
+			name: "unmasked byte differs",
+			con:  Content{Value: value, Mask: mask, WordSize: 4},
+			data: flip(host, unmasked),
+			want: false,
+		},
+		{
+			name: "value not a whole number of words",
+			con:  Content{Value: value[:6], WordSize: 4},
+			data: value[:6],
+			want: true,
+		},
+	}
+
+	for _, tt := range tests {
+		if got := tt.con.Matches(tt.data); got != tt.want {
+			t.Errorf("%s: Matches(% x) = %v, want %v", tt.name, tt.data, got, tt.want)
+		}
+	}
+}
+
+func flip(data []byte, i int) []byte {
+	flipped := append([]byte(nil), data...)
+	flipped[i] ^= 0xff
+	return flipped
+}
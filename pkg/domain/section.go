@@ -20,17 +20,135 @@ OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
 SOFTWARE.
 */package domain
 
+import "bytes"
+
 type Section struct {
 	Filetype string
 	Priority uint
 	Contents []*Content
+	Tree     []*Content
 }
 
 type Content struct {
 	Indent      uint
 	Offset      uint
+	OffsetEnd   uint
 	Value       []byte
 	Mask        []byte
 	RangeLength uint
 	WordSize    uint
+	Raw         []byte
+	Children    []*Content
+}
+
+type OwnedContent struct {
+	Filetype string
+	Priority uint
+	Content  *Content
+}
+
+// BuildTree links contents into a tree following their indents: a content
+// becomes a child of the closest preceding content with a smaller indent.
+// It returns the top-level contents and resets any previous Children.
+func BuildTree(cons []*Content) []*Content {
+	roots := make([]*Content, 0, len(cons))
+	parents := make([]*Content, 0, 4)
+
+	for _, con := range cons {
+		con.Children = nil
+
+		for len(parents) > 0 && parents[len(parents)-1].Indent >= con.Indent {
+			parents = parents[:len(parents)-1]
+		}
+
+		if len(parents) == 0 {
+			roots = append(roots, con)
+		} else {
+			parent := parents[len(parents)-1]
+			parent.Children = append(parent.Children, con)
+		}
+
+		parents = append(parents, con)
+	}
+
+	return roots
+}
+
+// Dedup removes contents that exactly repeat an earlier sibling under the
+// same parent, preserving order, and returns how many were removed. Only
+// leaf contents are removed so that nested rules keep their parents. Tree
+// is rebuilt from the remaining contents.
+func (s *Section) Dedup() int {
+	type ancestor struct {
+		pos    int
+		indent uint
+	}
+
+	kept := s.Contents[:0]
+	leaves := make(map[int][]*Content)
+	parents := make([]ancestor, 0, 4)
+	removed := 0
+
+	for i, con := range s.Contents {
+		for len(parents) > 0 && parents[len(parents)-1].indent >= con.Indent {
+			parents = parents[:len(parents)-1]
+		}
+		parent := -1
+		if len(parents) > 0 {
+			parent = parents[len(parents)-1].pos
+		}
+		parents = append(parents, ancestor{pos: i, indent: con.Indent})
+
+		if i+1 < len(s.Contents) && s.Contents[i+1].Indent > con.Indent {
+			kept = append(kept, con)
+			continue
+		}
+
+		duplicate := false
+		for _, leaf := range leaves[parent] {
+			if leaf.sameRule(con) {
+				duplicate = true
+				break
+			}
+		}
+		if duplicate {
+			removed++
+			continue
+		}
+
+		leaves[parent] = append(leaves[parent], con)
+		kept = append(kept, con)
+	}
+
+	for i := len(kept); i < len(s.Contents); i++ {
+		s.Contents[i] = nil
+	}
+	s.Contents = kept
+	s.Tree = BuildTree(s.Contents)
+
+	return removed
+}
+
+// Span returns how many consecutive offsets, starting at Offset, the value
+// is tried at. An offset range "start:end" covers the starts up to and
+// including end, and RangeLength extends the last of them.
+func (c *Content) Span() uint {
+	span := c.RangeLength
+	if span == 0 {
+		span = 1
+	}
+	if c.OffsetEnd > c.Offset {
+		span += c.OffsetEnd - c.Offset
+	}
+	return span
+}
+
+func (c *Content) sameRule(other *Content) bool {
+	return c.Indent == other.Indent &&
+		c.Offset == other.Offset &&
+		c.OffsetEnd == other.OffsetEnd &&
+		c.RangeLength == other.RangeLength &&
+		c.WordSize == other.WordSize &&
+		bytes.Equal(c.Value, other.Value) &&
+		bytes.Equal(c.Mask, other.Mask)
 }
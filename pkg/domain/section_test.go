@@ -0,0 +1,44 @@
+/*
+Copyright © 2023 Kovalev Pavel kovalev5690@gmail.com
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/package domain
+
+import "testing"
+
+func TestDedupRebuildsTree(t *testing.T) {
+	s := &Section{Contents: []*Content{
+		{Value: []byte("PK")},
+		{Indent: 1, Offset: 4, Value: []byte("A")},
+		{Indent: 1, Offset: 4, Value: []byte("A")},
+		{Offset: 8, Value: []byte("Z")},
+		{Offset: 8, Value: []byte("Z")},
+	}}
+	s.Tree = BuildTree(s.Contents)
+
+	if removed := s.Dedup(); removed != 2 {
+		t.Errorf("Dedup() = %d, want 2", removed)
+	}
+	if len(s.Tree) != 2 || s.Tree[1] != s.Contents[2] {
+		t.Fatalf("Tree = %v, want the 2 remaining top-level contents", s.Tree)
+	}
+	if children := s.Tree[0].Children; len(children) != 1 || children[0] != s.Contents[1] {
+		t.Errorf("Tree[0].Children = %v, want the remaining nested content", children)
+	}
+}
@@ -0,0 +1,81 @@
+/*
+Copyright © 2023 Kovalev Pavel kovalev5690@gmail.com
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/package domain
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// previewLen is how many bytes of a value or mask String prints in hex.
+const previewLen = 16
+
+// String summarizes the section like "[80:image/png] 3 contents".
+func (s *Section) String() string {
+	return fmt.Sprintf("[%d:%s] %d contents", s.Priority, s.Filetype, len(s.Contents))
+}
+
+// String renders the content close to its line in the magic file, with the
+// value in hex: "1>4=89504e47&ffffdfdf~2+8". The mask is left out when it
+// is all 0xff, and long values are cut short with "...".
+func (c *Content) String() string {
+	var sb strings.Builder
+
+	if c.Indent > 0 {
+		fmt.Fprintf(&sb, "%d", c.Indent)
+	}
+	fmt.Fprintf(&sb, ">%d", c.Offset)
+	if c.OffsetEnd > 0 {
+		fmt.Fprintf(&sb, ":%d", c.OffsetEnd)
+	}
+	sb.WriteByte('=')
+	sb.WriteString(hexPreview(c.Value))
+
+	if !fullMask(c.Mask) {
+		sb.WriteByte('&')
+		sb.WriteString(hexPreview(c.Mask))
+	}
+	if c.WordSize > 1 {
+		fmt.Fprintf(&sb, "~%d", c.WordSize)
+	}
+	if c.RangeLength > 1 {
+		fmt.Fprintf(&sb, "+%d", c.RangeLength)
+	}
+
+	return sb.String()
+}
+
+func hexPreview(b []byte) string {
+	if len(b) > previewLen {
+		return hex.EncodeToString(b[:previewLen]) + "..."
+	}
+	return hex.EncodeToString(b)
+}
+
+func fullMask(mask []byte) bool {
+	for _, b := range mask {
+		if b != 0xff {
+			return false
+		}
+	}
+	return true
+}
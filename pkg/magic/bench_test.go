@@ -0,0 +1,106 @@
+/*
+Copyright © 2023 Kovalev Pavel kovalev5690@gmail.com
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/package magic
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"testing"
+)
+
+// testdata/magic is a synthetic database shaped like the shared-mime-info
+// one: 470 sections with about 1100 contents, mostly at priority 50, with
+// nested rules, masks, word sizes and range rules.
+const fixturePath = "testdata/magic"
+
+func readFixture(b *testing.B) []byte {
+	b.Helper()
+
+	data, err := os.ReadFile(fixturePath)
+	if err != nil {
+		b.Fatal(err)
+	}
+	return data
+}
+
+func benchmarkReadSections(b *testing.B, data []byte) {
+	b.ReportAllocs()
+	b.SetBytes(int64(len(data)))
+
+	for i := 0; i < b.N; i++ {
+		r := NewMagicReaderFromReader(bytes.NewReader(data))
+		if err := r.Open(); err != nil {
+			b.Fatal(err)
+		}
+		if _, err := r.ReadSections(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkReadSections parses the fixture, where nearly every content
+// fits on its line.
+func BenchmarkReadSections(b *testing.B) {
+	benchmarkReadSections(b, readFixture(b))
+}
+
+// BenchmarkReadSectionsContinuation parses values and masks that all span
+// several lines, so every content goes through the slow path of readValue.
+func BenchmarkReadSectionsContinuation(b *testing.B) {
+	var buf bytes.Buffer
+	buf.WriteString(magicHeader)
+	for i := 0; i < 470; i++ {
+		fmt.Fprintf(&buf, "[50:x/continued-%d]\n", i)
+		for j := 0; j < 2; j++ {
+			buf.WriteString(">0=\x00\x10ab\ncd\nef\ngh\nijkl&\xff\n\xff\n\xff\n\xff\n\xff\xff\xff\xff\xff\xff\xff\xff\n")
+		}
+	}
+
+	benchmarkReadSections(b, buf.Bytes())
+}
+
+// BenchmarkReadSectionsLarge parses a database the size of several merged
+// system databases.
+func BenchmarkReadSectionsLarge(b *testing.B) {
+	data := readFixture(b)
+	large := append([]byte(nil), data...)
+	for i := 0; i < 7; i++ {
+		large = append(large, data[len(magicHeader):]...)
+	}
+
+	benchmarkReadSections(b, large)
+}
+
+func BenchmarkReadSectionsMmap(b *testing.B) {
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		r := NewMagicReaderMmap(fixturePath)
+		if err := r.Open(); err != nil {
+			b.Fatal(err)
+		}
+		if _, err := r.ReadSections(); err != nil {
+			b.Fatal(err)
+		}
+		r.Close()
+	}
+}
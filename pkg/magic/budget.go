@@ -0,0 +1,79 @@
+/*
+Copyright © 2023 Kovalev Pavel kovalev5690@gmail.com
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/package magic
+
+import (
+	"github.com/Pavel7004/goMimeMagic/pkg/domain"
+)
+
+// memoryBudget bounds the Value and Mask bytes retained by ReadSections.
+// When the limit is exceeded the lowest-priority sections are evicted, and
+// later sections are only admitted if they outrank everything evicted so
+// far. The result is the highest-priority part of the database that fits.
+type memoryBudget struct {
+	limit     int
+	used      int
+	threshold uint
+	truncated bool
+}
+
+func (b *memoryBudget) admit(sec *domain.Section) bool {
+	return b.limit <= 0 || !b.truncated || sec.Priority > b.threshold
+}
+
+// charge accounts the last section of secs, which was just appended, and
+// returns the retained sections. The new section itself may be evicted.
+func (b *memoryBudget) charge(secs []*domain.Section) []*domain.Section {
+	if b.limit <= 0 {
+		return secs
+	}
+
+	b.used += sectionSize(secs[len(secs)-1])
+
+	for b.used > b.limit && len(secs) > 0 {
+		idx := 0
+		for i, sec := range secs {
+			if sec.Priority <= secs[idx].Priority {
+				idx = i
+			}
+		}
+
+		evicted := secs[idx]
+		b.used -= sectionSize(evicted)
+		if !b.truncated || evicted.Priority > b.threshold {
+			b.threshold = evicted.Priority
+		}
+		b.truncated = true
+
+		secs = append(secs[:idx], secs[idx+1:]...)
+	}
+
+	return secs
+}
+
+// sectionSize is the number of Value and Mask bytes sec holds.
+func sectionSize(sec *domain.Section) int {
+	size := 0
+	for _, con := range sec.Contents {
+		size += len(con.Value) + len(con.Mask)
+	}
+	return size
+}
@@ -0,0 +1,66 @@
+/*
+Copyright © 2023 Kovalev Pavel kovalev5690@gmail.com
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/package magic
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestMemoryBudgetTiny(t *testing.T) {
+	// Every 4-byte value comes with a 4-byte default mask.
+	data := magicHeader +
+		"[50:x/a]\n>0=\x00\x04AAAA\n" +
+		"[40:x/b]\n>0=\x00\x04BBBB\n>4=\x00\x04bbbb\n" +
+		"[60:x/c]\n>0=\x00\x04CCCC\n" +
+		"[70:x/d]\n>0=\x00\x02DD\n"
+
+	secs, err := readSections(t, strings.NewReader(data), WithMemoryBudget(10))
+	if !errors.Is(err, ErrBudgetExceeded) {
+		t.Fatalf("ReadSections() error = %v, want %v", err, ErrBudgetExceeded)
+	}
+
+	used := 0
+	for _, sec := range secs {
+		used += sectionSize(sec)
+	}
+	if used > 10 {
+		t.Errorf("kept %d bytes, want at most 10", used)
+	}
+	if len(secs) != 1 || secs[0].Filetype != "x/d" {
+		t.Errorf("ReadSections() = %v, want only x/d", secs)
+	}
+}
+
+func TestMemoryBudgetFits(t *testing.T) {
+	data := magicHeader +
+		"[50:x/a]\n>0=\x00\x04AAAA\n" +
+		"[40:x/b]\n>0=\x00\x02BB\n"
+
+	secs, err := readSections(t, strings.NewReader(data), WithMemoryBudget(12))
+	if err != nil {
+		t.Fatalf("ReadSections() error = %v", err)
+	}
+	if len(secs) != 2 {
+		t.Errorf("ReadSections() = %v, want both sections", secs)
+	}
+}
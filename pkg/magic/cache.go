@@ -0,0 +1,155 @@
+/*
+Copyright © 2023 Kovalev Pavel kovalev5690@gmail.com
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/package magic
+
+import (
+	"encoding/gob"
+	"errors"
+	"os"
+	"path/filepath"
+
+	"github.com/Pavel7004/goMimeMagic/pkg/domain"
+)
+
+var ErrCacheVersion = errors.New("Cache file has unsupported version")
+
+// cacheVersion is bumped whenever domain types change in a way that makes
+// old cache files unreadable.
+const cacheVersion = 2
+
+type cacheFile struct {
+	Version  int
+	Source   string
+	Sections []*domain.Section
+}
+
+// SaveCache writes sections read from the magic file at sourcePath to path
+// in gob format. The file is replaced atomically, so a concurrent LoadCache
+// never sees a partial cache.
+func SaveCache(sections []*domain.Section, path, sourcePath string) error {
+	source, err := filepath.Abs(sourcePath)
+	if err != nil {
+		return err
+	}
+
+	flat := make([]*domain.Section, 0, len(sections))
+	for _, sec := range sections {
+		cp := *sec
+		cp.Tree = nil
+		cp.Contents = make([]*domain.Content, 0, len(sec.Contents))
+		for _, con := range sec.Contents {
+			c := *con
+			c.Children = nil
+			cp.Contents = append(cp.Contents, &c)
+		}
+		flat = append(flat, &cp)
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if err := gob.NewEncoder(f).Encode(cacheFile{Version: cacheVersion, Source: source, Sections: flat}); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(f.Name(), path)
+}
+
+// LoadCache reads sections written by SaveCache.
+func LoadCache(path string) ([]*domain.Section, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var cache cacheFile
+	if err := gob.NewDecoder(f).Decode(&cache); err != nil {
+		return nil, err
+	}
+	if cache.Version != cacheVersion {
+		return nil, ErrCacheVersion
+	}
+
+	for _, sec := range cache.Sections {
+		sec.Tree = domain.BuildTree(sec.Contents)
+	}
+
+	return cache.Sections, nil
+}
+
+// CacheIsFresh reports whether the cache at cachePath was written from the
+// magic file at sourcePath after it was last modified. Equal modification
+// times count as stale, since coarse timestamps can't order them. A
+// missing cache, or one written from another file or by another version,
+// is reported as stale.
+func CacheIsFresh(cachePath, sourcePath string) (bool, error) {
+	cache, err := os.Stat(cachePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	source, err := os.Stat(sourcePath)
+	if err != nil {
+		return false, err
+	}
+
+	if !cache.ModTime().After(source.ModTime()) {
+		return false, nil
+	}
+
+	return cacheSourceIs(cachePath, sourcePath)
+}
+
+// cacheSourceIs reports whether the cache at cachePath was written from
+// sourcePath. The sections are skipped by the decoder, not built.
+func cacheSourceIs(cachePath, sourcePath string) (bool, error) {
+	source, err := filepath.Abs(sourcePath)
+	if err != nil {
+		return false, err
+	}
+
+	f, err := os.Open(cachePath)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	var header struct {
+		Version int
+		Source  string
+	}
+	if err := gob.NewDecoder(f).Decode(&header); err != nil {
+		return false, err
+	}
+
+	return header.Version == cacheVersion && header.Source == source, nil
+}
@@ -0,0 +1,69 @@
+/*
+Copyright © 2023 Kovalev Pavel kovalev5690@gmail.com
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/package magic
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCacheIsFreshChecksSource(t *testing.T) {
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a.magic")
+	b := filepath.Join(dir, "b.magic")
+	cache := filepath.Join(dir, "magic.cache")
+
+	old := time.Now().Add(-time.Hour)
+	for _, path := range []string{a, b} {
+		if err := os.WriteFile(path, []byte(magicHeader+"[50:x/test]\n>0=\x00\x01A\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, old, old); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	secs, err := readFile(NewMagicReaderWithPath(a))
+	if err != nil {
+		t.Fatalf("ReadSections() error = %v", err)
+	}
+	if err := SaveCache(secs, cache, a); err != nil {
+		t.Fatalf("SaveCache() error = %v", err)
+	}
+
+	// b is older than the cache too, but the cache holds a's sections.
+	for path, want := range map[string]bool{a: true, b: false} {
+		fresh, err := CacheIsFresh(cache, path)
+		if err != nil || fresh != want {
+			t.Errorf("CacheIsFresh(%s) = %v, %v, want %v", filepath.Base(path), fresh, err, want)
+		}
+	}
+
+	loaded, err := LoadCache(cache)
+	if err != nil {
+		t.Fatalf("LoadCache() error = %v", err)
+	}
+	if len(loaded) != 1 || !loaded[0].Equal(secs[0]) {
+		t.Errorf("LoadCache() = %v, want %v", loaded, secs)
+	}
+}
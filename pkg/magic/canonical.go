@@ -0,0 +1,112 @@
+/*
+Copyright © 2023 Kovalev Pavel kovalev5690@gmail.com
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/package magic
+
+import (
+	"bytes"
+	"sort"
+
+	"github.com/Pavel7004/goMimeMagic/pkg/domain"
+)
+
+// CanonicalSections returns a deep copy of secs in a stable order: sections
+// by filetype and then by descending priority, contents by offset and then
+// by value. Contents are only reordered among siblings, and nested contents
+// move together with their parent, so the rules keep their meaning.
+func CanonicalSections(secs []*domain.Section) []*domain.Section {
+	res := make([]*domain.Section, 0, len(secs))
+	for _, sec := range secs {
+		cons := canonicalContents(sec.Contents)
+		res = append(res, &domain.Section{
+			Filetype: sec.Filetype,
+			Priority: sec.Priority,
+			Contents: cons,
+			Tree:     domain.BuildTree(cons),
+		})
+	}
+
+	sort.SliceStable(res, func(i, j int) bool {
+		if res[i].Filetype != res[j].Filetype {
+			return res[i].Filetype < res[j].Filetype
+		}
+		return res[i].Priority > res[j].Priority
+	})
+
+	return res
+}
+
+func canonicalContents(cons []*domain.Content) []*domain.Content {
+	groups := make([][]*domain.Content, 0, len(cons))
+	for i := 0; i < len(cons); {
+		end := i + 1
+		for end < len(cons) && cons[end].Indent > cons[i].Indent {
+			end++
+		}
+
+		group := make([]*domain.Content, 0, end-i)
+		group = append(group, copyContent(cons[i]))
+		group = append(group, canonicalContents(cons[i+1:end])...)
+		groups = append(groups, group)
+
+		i = end
+	}
+
+	sort.SliceStable(groups, func(i, j int) bool {
+		return lessContent(groups[i][0], groups[j][0])
+	})
+
+	res := make([]*domain.Content, 0, len(cons))
+	for _, group := range groups {
+		res = append(res, group...)
+	}
+
+	return res
+}
+
+func lessContent(a, b *domain.Content) bool {
+	if a.Offset != b.Offset {
+		return a.Offset < b.Offset
+	}
+	if c := bytes.Compare(a.Value, b.Value); c != 0 {
+		return c < 0
+	}
+	if c := bytes.Compare(a.Mask, b.Mask); c != 0 {
+		return c < 0
+	}
+	if a.OffsetEnd != b.OffsetEnd {
+		return a.OffsetEnd < b.OffsetEnd
+	}
+	if a.RangeLength != b.RangeLength {
+		return a.RangeLength < b.RangeLength
+	}
+	return a.WordSize < b.WordSize
+}
+
+func copyContent(con *domain.Content) *domain.Content {
+	cp := *con
+	cp.Value = append([]byte(nil), con.Value...)
+	cp.Mask = append([]byte(nil), con.Mask...)
+	if con.Raw != nil {
+		cp.Raw = append([]byte(nil), con.Raw...)
+	}
+	cp.Children = nil
+	return &cp
+}
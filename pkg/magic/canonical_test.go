@@ -0,0 +1,73 @@
+/*
+Copyright © 2023 Kovalev Pavel kovalev5690@gmail.com
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/package magic
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCanonicalSections(t *testing.T) {
+	data := magicHeader +
+		"[50:x/b]\n>0=\x00\x01B\n" +
+		"[60:x/a]\n>8=\x00\x01Z\n1>9=\x00\x01Y\n1>4=\x00\x01X\n>0=\x00\x01A\n1>2=\x00\x01W\n" +
+		"[70:x/b]\n>0=\x00\x01C\n"
+
+	secs, err := readSections(t, strings.NewReader(data))
+	if err != nil {
+		t.Fatalf("ReadSections() error = %v", err)
+	}
+
+	once := CanonicalSections(secs)
+	twice := CanonicalSections(once)
+
+	if len(twice) != len(once) {
+		t.Fatalf("canonicalizing twice gave %d sections, want %d", len(twice), len(once))
+	}
+	for i := range once {
+		if !twice[i].Equal(once[i]) {
+			t.Errorf("section %d canonicalized twice = %v, want %v", i, twice[i], once[i])
+		}
+	}
+
+	var order []string
+	for _, sec := range once {
+		order = append(order, sec.String())
+	}
+	if want := "[60:x/a] 5 contents, [70:x/b] 1 contents, [50:x/b] 1 contents"; strings.Join(order, ", ") != want {
+		t.Errorf("section order = %v, want %s", order, want)
+	}
+
+	var cons []string
+	for _, con := range once[0].Contents {
+		cons = append(cons, con.String())
+	}
+	if want := ">0=41 1>2=57 >8=5a 1>4=58 1>9=59"; strings.Join(cons, " ") != want {
+		t.Errorf("contents = %v, want %s", cons, want)
+	}
+
+	// The nested rules are still the children of their parents.
+	tree := once[0].Tree
+	if len(tree) != 2 || len(tree[0].Children) != 1 || string(tree[0].Children[0].Value) != "W" ||
+		len(tree[1].Children) != 2 || string(tree[1].Children[1].Value) != "Y" {
+		t.Errorf("Tree does not keep the nested rules under their parents: %v", tree)
+	}
+}
@@ -0,0 +1,283 @@
+/*
+Copyright © 2023 Kovalev Pavel kovalev5690@gmail.com
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/package magic
+
+import (
+	"errors"
+	"io"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/Pavel7004/goMimeMagic/pkg/domain"
+)
+
+var ErrNoMatch = errors.New("No matching type found")
+
+const (
+	// sniffLen is how many bytes http.DetectContentType looks at.
+	sniffLen = 512
+
+	defaultContentType = "application/octet-stream"
+)
+
+type Result struct {
+	Filetype string
+	Priority uint
+}
+
+// Database matches data against parsed sections. Matching only reads the
+// sections and keeps per-call state on the stack, so a Database is safe
+// for concurrent use by multiple goroutines as long as nobody modifies
+// its sections. A MagicReader, on the other hand, is not.
+type Database struct {
+	Sections []*domain.Section
+
+	overrides []*domain.Section
+	index     *firstByteIndex
+
+	filterOnce sync.Once
+	filter     [256]bool
+
+	prefixOnce sync.Once
+	prefixLen  int
+}
+
+// NewDatabase indexes secs for matching. The sections must not be changed
+// afterwards, or the index goes stale.
+func NewDatabase(secs []*domain.Section) *Database {
+	return &Database{
+		Sections: secs,
+		index:    newFirstByteIndex(secs),
+	}
+}
+
+// WithOverride returns a database that checks sec before any other
+// section, regardless of priorities. db itself is left unchanged.
+// Overrides added later are checked first.
+func (db *Database) WithOverride(sec *domain.Section) *Database {
+	overrides := make([]*domain.Section, 0, len(db.overrides)+1)
+	overrides = append(overrides, sec)
+	overrides = append(overrides, db.overrides...)
+
+	return &Database{
+		Sections:  db.Sections,
+		overrides: overrides,
+		index:     db.index,
+	}
+}
+
+// Match returns the filetype of the highest-priority section that has a
+// rule matching data. Among sections of equal priority the first one
+// wins. ErrNoMatch is returned when no section matches.
+func (db *Database) Match(data []byte) (string, error) {
+	sec, err := db.MatchSection(data)
+	if err != nil {
+		return "", err
+	}
+	return sec.Filetype, nil
+}
+
+// MatchSection is like Match but returns the whole winning section.
+func (db *Database) MatchSection(data []byte) (*domain.Section, error) {
+	secs := db.matchAll(data)
+	if len(secs) == 0 {
+		return nil, ErrNoMatch
+	}
+	return secs[0], nil
+}
+
+// MatchAll returns every section matching data, highest priority first.
+// Sections of equal priority keep their database order, and overrides come
+// before all of them.
+func (db *Database) MatchAll(data []byte) []Result {
+	secs := db.matchAll(data)
+
+	res := make([]Result, 0, len(secs))
+	for _, sec := range secs {
+		res = append(res, Result{
+			Filetype: sec.Filetype,
+			Priority: sec.Priority,
+		})
+	}
+
+	return res
+}
+
+func (db *Database) matchAll(data []byte) []*domain.Section {
+	var secs []*domain.Section
+	for _, sec := range db.overrides {
+		if matchSection(sec, data) {
+			secs = append(secs, sec)
+		}
+	}
+	overrides := len(secs)
+
+	if db.index == nil {
+		for _, sec := range db.Sections {
+			if matchSection(sec, data) {
+				secs = append(secs, sec)
+			}
+		}
+	} else {
+		for _, i := range db.index.candidates(data) {
+			if sec := db.Sections[i]; matchSection(sec, data) {
+				secs = append(secs, sec)
+			}
+		}
+	}
+
+	matched := secs[overrides:]
+	sort.SliceStable(matched, func(i, j int) bool {
+		return matched[i].Priority > matched[j].Priority
+	})
+
+	return secs
+}
+
+// DetectFile detects the type of the file at path, reading it as
+// DetectReader does. Errors from opening or reading the file are returned
+// as is.
+func (db *Database) DetectFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	return db.DetectReader(f)
+}
+
+// DetectReader detects the type of the data read from r. It consumes at
+// most MaxPrefixLen bytes, and less if the stream ends first. Signatures
+// that do not fit into a short stream simply don't match. Read errors are
+// returned as is.
+func (db *Database) DetectReader(r io.Reader) (string, error) {
+	buff := make([]byte, db.MaxPrefixLen())
+	n, err := io.ReadFull(r, buff)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return "", err
+	}
+
+	return db.Match(buff[:n])
+}
+
+// DetectContentType is a replacement for http.DetectContentType backed by
+// the magic database. Like the stdlib it considers at most the first 512
+// bytes of data, or more if a signature reaches further, and returns
+// "application/octet-stream" when no type matches.
+func (db *Database) DetectContentType(data []byte) string {
+	limit := sniffLen
+	if n := db.MaxPrefixLen(); n > limit {
+		limit = n
+	}
+	if len(data) > limit {
+		data = data[:limit]
+	}
+
+	filetype, err := db.Match(data)
+	if err != nil {
+		return defaultContentType
+	}
+	return filetype
+}
+
+// MaxPrefixLen returns how many leading bytes of a file the database can
+// look at: the largest Offset + Span() - 1 + len(Value) over all contents,
+// overrides included. Masks and word sizes don't change it, as a mask
+// covers exactly the value bytes and byte swapping stays within them. The
+// result is computed once.
+func (db *Database) MaxPrefixLen() int {
+	db.prefixOnce.Do(func() {
+		prefix := uint(0)
+		for _, secs := range [][]*domain.Section{db.overrides, db.Sections} {
+			for _, sec := range secs {
+				for _, con := range sec.Contents {
+					end := con.Offset + con.Span() - 1 + uint(len(con.Value))
+					if end > prefix {
+						prefix = end
+					}
+				}
+			}
+		}
+		db.prefixLen = int(prefix)
+	})
+	return db.prefixLen
+}
+
+// FirstByteFilter reports which first bytes can start data matched by a
+// top-level rule anchored exactly at offset 0. Rules at other offsets and
+// range rules are not taken into account.
+func (db *Database) FirstByteFilter() [256]bool {
+	db.filterOnce.Do(func() {
+		for _, secs := range [][]*domain.Section{db.overrides, db.Sections} {
+			for _, sec := range secs {
+				for _, con := range sec.Contents {
+					if con.Indent != 0 || con.Offset != 0 || con.Span() > 1 || len(con.Value) == 0 {
+						continue
+					}
+
+					value, mask := con.WordByte(0)
+					for b := 0; b < 256; b++ {
+						if byte(b)&mask == value&mask {
+							db.filter[b] = true
+						}
+					}
+				}
+			}
+		}
+	})
+	return db.filter
+}
+
+// MightMatch is a quick pre-check built on FirstByteFilter. A false result
+// means no offset 0 rule can match data; rules anchored elsewhere still
+// might, so it is only a safe negative for offset 0 signatures.
+func (db *Database) MightMatch(data []byte) bool {
+	if len(data) == 0 {
+		return false
+	}
+	filter := db.FirstByteFilter()
+	return filter[data[0]]
+}
+
+func matchSection(sec *domain.Section, data []byte) bool {
+	return matchRules(sec.Contents, data)
+}
+
+// matchRules walks the indent tree of cons, which must start at its lowest
+// indent. A rule matches when its content matches and, if it has nested
+// rules, at least one of them matches too.
+func matchRules(cons []*domain.Content, data []byte) bool {
+	for i := 0; i < len(cons); {
+		end := i + 1
+		for end < len(cons) && cons[end].Indent > cons[i].Indent {
+			end++
+		}
+
+		if cons[i].Matches(data) && (end == i+1 || matchRules(cons[i+1:end], data)) {
+			return true
+		}
+
+		i = end
+	}
+	return false
+}
@@ -0,0 +1,271 @@
+/*
+Copyright © 2023 Kovalev Pavel kovalev5690@gmail.com
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/package magic
+
+import (
+	"bytes"
+	"errors"
+	"math/rand"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Pavel7004/goMimeMagic/pkg/domain"
+)
+
+func fixtureSections(tb testing.TB) []*domain.Section {
+	tb.Helper()
+
+	r := NewMagicReaderWithPath(fixturePath)
+	if err := r.Open(); err != nil {
+		tb.Fatal(err)
+	}
+	defer r.Close()
+
+	secs, err := r.ReadSections()
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return secs
+}
+
+// randomInputs returns n buffers of size random bytes. The seed is fixed
+// so runs are comparable.
+func randomInputs(n, size int) [][]byte {
+	rnd := rand.New(rand.NewSource(1))
+
+	inputs := make([][]byte, n)
+	for i := range inputs {
+		inputs[i] = make([]byte, size)
+		rnd.Read(inputs[i])
+	}
+	return inputs
+}
+
+func benchmarkMatch(b *testing.B, db *Database) {
+	inputs := randomInputs(64, 1024)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		db.Match(inputs[i%len(inputs)])
+	}
+}
+
+// BenchmarkMatchLinear and BenchmarkMatchIndexed compare a database
+// without the first-byte index to one built by NewDatabase.
+func BenchmarkMatchLinear(b *testing.B) {
+	benchmarkMatch(b, &Database{Sections: fixtureSections(b)})
+}
+
+func BenchmarkMatchIndexed(b *testing.B) {
+	benchmarkMatch(b, NewDatabase(fixtureSections(b)))
+}
+
+// wellKnown is a handful of real offset 0 signatures. The fixture values
+// are random and leave no first byte unused, so it can't show a skip rate.
+var wellKnown = []*domain.Section{
+	signature("image/png", "\x89PNG\r\n\x1a\n"),
+	signature("image/gif", "GIF8"),
+	signature("image/jpeg", "\xff\xd8\xff"),
+	signature("application/pdf", "%PDF-"),
+	signature("application/zip", "PK\x03\x04"),
+	signature("application/gzip", "\x1f\x8b"),
+	signature("application/x-executable", "\x7fELF"),
+	signature("application/xml", "<?xml"),
+}
+
+func signature(filetype, value string) *domain.Section {
+	return &domain.Section{
+		Filetype: filetype,
+		Priority: 50,
+		Contents: []*domain.Content{{
+			Value: []byte(value),
+			Mask:  bytes.Repeat([]byte{0xff}, len(value)),
+		}},
+	}
+}
+
+// BenchmarkMightMatch reports which share of random inputs MightMatch
+// lets callers skip.
+func BenchmarkMightMatch(b *testing.B) {
+	db := NewDatabase(wellKnown)
+	inputs := randomInputs(256, 16)
+	db.FirstByteFilter()
+
+	skipped := 0
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if !db.MightMatch(inputs[i%len(inputs)]) {
+			skipped++
+		}
+	}
+	b.ReportMetric(float64(skipped)/float64(b.N), "skip-rate")
+}
+
+// TestDatabaseConcurrentUse is meant for go test -race. The database is
+// fresh, so the goroutines also race on the first FirstByteFilter and
+// MaxPrefixLen calls.
+func TestDatabaseConcurrentUse(t *testing.T) {
+	secs := append(fixtureSections(t), wellKnown...)
+	png := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+	want, err := NewDatabase(secs).Match(png)
+	if err != nil {
+		t.Fatalf("Match() error = %v", err)
+	}
+
+	db := NewDatabase(secs)
+	inputs := append(randomInputs(8, 1024), png)
+
+	var wg sync.WaitGroup
+	for g := 0; g < 16; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+
+			for i := 0; i < 20; i++ {
+				data := inputs[(g+i)%len(inputs)]
+				switch i % 4 {
+				case 0:
+					db.Match(data)
+					db.MatchAll(data)
+				case 1:
+					db.MightMatch(data)
+				case 2:
+					db.DetectContentType(data)
+				case 3:
+					if got, err := db.Match(png); err != nil || got != want {
+						t.Errorf("Match(png) = %q, %v, want %q", got, err, want)
+					}
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	if n := db.MaxPrefixLen(); n != NewDatabase(secs).MaxPrefixLen() {
+		t.Errorf("MaxPrefixLen() = %d after concurrent use", n)
+	}
+}
+
+func TestMatchShortData(t *testing.T) {
+	far := func(con *domain.Content) *domain.Section {
+		return &domain.Section{Filetype: "x/far", Priority: 50, Contents: []*domain.Content{con}}
+	}
+	secs := []*domain.Section{
+		far(&domain.Content{Offset: 512, Value: []byte("ABCD")}),
+		far(&domain.Content{Offset: 512, Value: []byte("ABCD"), Mask: []byte{0xff, 0xdf, 0xff, 0xdf}}),
+		far(&domain.Content{Offset: 512, Value: []byte("ABCD"), WordSize: 2}),
+		far(&domain.Content{Offset: 512, Value: []byte("AB"), RangeLength: 64}),
+		far(&domain.Content{Offset: 0, Value: []byte("ABCD"), RangeLength: 600}),
+		{Filetype: "x/nested", Priority: 50, Contents: []*domain.Content{
+			{Value: []byte("AB")},
+			{Indent: 1, Offset: 512, Value: []byte("C")},
+		}},
+	}
+
+	for name, db := range map[string]*Database{"indexed": NewDatabase(secs), "linear": {Sections: secs}} {
+		if got, err := db.Match([]byte("ABX")); !errors.Is(err, ErrNoMatch) {
+			t.Errorf("%s: Match() = %q, %v, want %v", name, got, err, ErrNoMatch)
+		}
+	}
+}
+
+func TestMatchNestedRules(t *testing.T) {
+	data := magicHeader +
+		"[60:x/doc]\n>0=\x00\x02PK\n1>4=\x00\x03DOC\n2>8=\x00\x01!\n" +
+		"[50:x/zip]\n>0=\x00\x02PK\n"
+
+	secs, err := readSections(t, strings.NewReader(data))
+	if err != nil {
+		t.Fatalf("ReadSections() error = %v", err)
+	}
+
+	tests := []struct {
+		data string
+		want string
+	}{
+		{data: "PK..DOC.!", want: "x/doc"},
+		{data: "PK..DOC.?", want: "x/zip"},
+		{data: "PK..XLS.!", want: "x/zip"},
+		{data: "PK", want: "x/zip"},
+	}
+
+	for name, db := range map[string]*Database{"indexed": NewDatabase(secs), "linear": {Sections: secs}} {
+		for _, tt := range tests {
+			if got, err := db.Match([]byte(tt.data)); err != nil || got != tt.want {
+				t.Errorf("%s: Match(%q) = %q, %v, want %q", name, tt.data, got, err, tt.want)
+			}
+		}
+	}
+}
+
+func TestWithOverride(t *testing.T) {
+	base := NewDatabase(wellKnown)
+	custom := signature("image/x-custom-png", "\x89PNG")
+	custom.Priority = 10
+
+	db := base.WithOverride(custom)
+	png := []byte("\x89PNG\r\n\x1a\n")
+
+	if got, err := db.Match(png); err != nil || got != "image/x-custom-png" {
+		t.Errorf("override Match() = %q, %v, want image/x-custom-png", got, err)
+	}
+	if got, err := base.Match(png); err != nil || got != "image/png" {
+		t.Errorf("base Match() = %q, %v, want image/png", got, err)
+	}
+	if len(base.MatchAll(png)) != 1 {
+		t.Errorf("base MatchAll() = %v, want only image/png", base.MatchAll(png))
+	}
+}
+
+func TestMatchSection(t *testing.T) {
+	low := signature("application/zip", "PK")
+	low.Priority = 40
+	high := signature("application/x-docx", "PK\x03\x04")
+	high.Priority = 80
+	db := NewDatabase([]*domain.Section{low, high})
+
+	tests := []struct {
+		data     string
+		filetype string
+		priority uint
+	}{
+		{data: "PK\x03\x04", filetype: "application/x-docx", priority: 80},
+		{data: "PK\x05\x06", filetype: "application/zip", priority: 40},
+	}
+	for _, tt := range tests {
+		sec, err := db.MatchSection([]byte(tt.data))
+		if err != nil {
+			t.Fatalf("MatchSection(%q) error = %v", tt.data, err)
+		}
+		if filetype, _ := db.Match([]byte(tt.data)); filetype != sec.Filetype {
+			t.Errorf("MatchSection(%q) = %v, Match() = %q", tt.data, sec, filetype)
+		}
+		if sec.Filetype != tt.filetype || sec.Priority != tt.priority {
+			t.Errorf("MatchSection(%q) = %v, want [%d:%s]", tt.data, sec, tt.priority, tt.filetype)
+		}
+	}
+
+	if _, err := db.MatchSection([]byte("GIF8")); !errors.Is(err, ErrNoMatch) {
+		t.Errorf("MatchSection() error = %v, want %v", err, ErrNoMatch)
+	}
+}
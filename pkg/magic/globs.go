@@ -0,0 +1,129 @@
+/*
+Copyright © 2023 Kovalev Pavel kovalev5690@gmail.com
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/package magic
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/Pavel7004/goMimeMagic/pkg/domain"
+)
+
+var ErrGlobCorrupted = errors.New("Glob line is not readable")
+
+const DefaultGlobsPath = "/usr/share/mime/globs"
+
+// noGlobs is the pattern the spec uses to drop the globs of a type that
+// were defined earlier.
+const noGlobs = "__NOGLOBS__"
+
+// ReadGlobs parses the shared-mime-info globs file at path.
+func ReadGlobs(path string) ([]*domain.Glob, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return ParseGlobs(f)
+}
+
+// ParseGlobs parses "type:pattern" lines. Comments and empty lines are
+// skipped.
+func ParseGlobs(src io.Reader) ([]*domain.Glob, error) {
+	globs := make([]*domain.Glob, 0, 64)
+
+	scanner := bufio.NewScanner(src)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" || line[0] == '#' {
+			continue
+		}
+
+		filetype, pattern, ok := strings.Cut(line, ":")
+		if !ok || filetype == "" || pattern == "" {
+			return nil, ErrGlobCorrupted
+		}
+
+		if pattern == noGlobs {
+			kept := globs[:0]
+			for _, g := range globs {
+				if g.Filetype != filetype {
+					kept = append(kept, g)
+				}
+			}
+			globs = kept
+			continue
+		}
+
+		globs = append(globs, &domain.Glob{
+			Filetype: filetype,
+			Pattern:  pattern,
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return globs, nil
+}
+
+type GlobTable struct {
+	Globs []*domain.Glob
+}
+
+func NewGlobTable(globs []*domain.Glob) *GlobTable {
+	return &GlobTable{
+		Globs: globs,
+	}
+}
+
+// ExtensionsFor returns the extensions of filetype, with the leading dot
+// like mime.ExtensionsByType, in globs file order. Only plain "*.ext"
+// patterns count as extensions.
+func (t *GlobTable) ExtensionsFor(filetype string) []string {
+	var exts []string
+	for _, g := range t.Globs {
+		if g.Filetype != filetype {
+			continue
+		}
+
+		ext := strings.TrimPrefix(g.Pattern, "*")
+		if len(ext) < 2 || ext[0] != '.' || strings.ContainsAny(ext, "*?[") {
+			continue
+		}
+
+		dup := false
+		for _, e := range exts {
+			if e == ext {
+				dup = true
+				break
+			}
+		}
+		if !dup {
+			exts = append(exts, ext)
+		}
+	}
+	return exts
+}
@@ -0,0 +1,98 @@
+/*
+Copyright © 2023 Kovalev Pavel kovalev5690@gmail.com
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/package magic
+
+import (
+	"github.com/Pavel7004/goMimeMagic/pkg/domain"
+)
+
+// firstByteIndex groups sections by the first data bytes their top-level
+// rules accept, so matching only runs the rules of sections that can match.
+// Sections with a top-level rule that is not anchored exactly at offset 0
+// can't be indexed and are always tried. Both lists hold section indexes
+// in ascending order.
+type firstByteIndex struct {
+	byByte [256][]int
+	always []int
+}
+
+func newFirstByteIndex(secs []*domain.Section) *firstByteIndex {
+	idx := new(firstByteIndex)
+	for i, sec := range secs {
+		accepted, ok := firstBytes(sec.Contents)
+		if !ok {
+			idx.always = append(idx.always, i)
+			continue
+		}
+
+		for b := range accepted {
+			if accepted[b] {
+				idx.byByte[b] = append(idx.byByte[b], i)
+			}
+		}
+	}
+	return idx
+}
+
+// firstBytes reports which first bytes of data some top-level rule of cons
+// accepts. It fails if a top-level rule looks beyond offset 0.
+func firstBytes(cons []*domain.Content) ([256]bool, bool) {
+	var accepted [256]bool
+	for i := 0; i < len(cons); {
+		con := cons[i]
+		if con.Offset != 0 || con.Span() > 1 || len(con.Value) == 0 {
+			return accepted, false
+		}
+
+		value, mask := con.WordByte(0)
+		for b := 0; b < 256; b++ {
+			if byte(b)&mask == value&mask {
+				accepted[b] = true
+			}
+		}
+
+		i++
+		for i < len(cons) && cons[i].Indent > con.Indent {
+			i++
+		}
+	}
+	return accepted, true
+}
+
+// candidates returns the indexes of the sections that might match data,
+// in ascending order.
+func (idx *firstByteIndex) candidates(data []byte) []int {
+	if len(data) == 0 {
+		return idx.always
+	}
+
+	a, b := idx.always, idx.byByte[data[0]]
+	merged := make([]int, 0, len(a)+len(b))
+	for len(a) > 0 && len(b) > 0 {
+		if a[0] < b[0] {
+			merged, a = append(merged, a[0]), a[1:]
+		} else {
+			merged, b = append(merged, b[0]), b[1:]
+		}
+	}
+	merged = append(merged, a...)
+	return append(merged, b...)
+}
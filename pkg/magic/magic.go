@@ -25,11 +25,13 @@ import (
 	"bytes"
 	"encoding/binary"
 	"errors"
+	"fmt"
 	"io"
+	"io/fs"
 	"log"
 	"os"
 	"strconv"
-	"unicode"
+	"time"
 
 	"github.com/Pavel7004/goMimeMagic/pkg/domain"
 )
@@ -39,72 +41,315 @@ var (
 	ErrHeaderCorrupted    = errors.New("Section header is not readable")
 	ErrContentCorrupted   = errors.New("Section content is not readable")
 	ErrTokenNotFound      = errors.New("Token not found")
+	ErrTarEntryNotFound   = errors.New("Entry not found in tar archive")
+	ErrNotOpened          = errors.New("Magic file is not opened")
+	ErrReadTimeout        = errors.New("Read from magic file timed out")
+	ErrBudgetExceeded     = errors.New("Memory budget exceeded, database truncated")
+
+	errMalformedToken = fmt.Errorf("%w: malformed number", ErrContentCorrupted)
+	errParentSkipped  = fmt.Errorf("%w: parent line skipped", ErrContentCorrupted)
+)
+
+const (
+	DefaultPath     = "/usr/share/mime/magic"
+	FormatMIMEMagic = "MIME-Magic"
 )
 
+type HeaderInfo struct {
+	Format  string
+	Version []byte
+}
+
+type Warning struct {
+	Filetype string
+	Line     []byte
+	Err      error
+}
+
+type skippedContentError struct {
+	line   []byte
+	indent uint
+	err    error
+}
+
+func (e *skippedContentError) Error() string {
+	return e.err.Error()
+}
+
+func (e *skippedContentError) Unwrap() error {
+	return e.err
+}
+
+// lineReader is the part of bufio.Reader the parser uses. It lets a mapped
+// file hand out lines straight from the mapping.
+type lineReader interface {
+	io.Reader
+	ReadSlice(delim byte) ([]byte, error)
+	ReadBytes(delim byte) ([]byte, error)
+	Peek(n int) ([]byte, error)
+}
+
 type MagicReader struct {
 	Filename string
 
-	reader *bufio.Reader
-	file   *os.File
+	reader lineReader
+	src    io.Reader
+	fsys   fs.FS
+	file   io.Closer
+
+	rawContent       bool
+	maxIndent        uint
+	maxValueLen      uint
+	lenientOffsets   bool
+	trailingComments bool
+	readTimeout      time.Duration
+	memoryBudget     int
+	useMmap          bool
+	logger           *log.Logger
+
+	header   *HeaderInfo
+	warnings []Warning
+
+	// atEOF is set once a line was cut short by the end of the file and
+	// got its '\n' appended by the reader.
+	atEOF bool
+}
+
+func NewMagicReader(opts ...Option) *MagicReader {
+	return NewMagicReaderWithPath(DefaultPath, opts...)
 }
 
-func NewMagicReader() *MagicReader {
-	return &MagicReader{
-		Filename: "/usr/share/mime/magic",
+func NewMagicReaderWithPath(path string, opts ...Option) *MagicReader {
+	r := &MagicReader{
+		Filename:    path,
+		maxIndent:   16,
+		maxValueLen: 4096,
+		logger:      log.New(io.Discard, "", 0),
+	}
+	for _, opt := range opts {
+		opt(r)
 	}
+	return r
+}
+
+// NewMagicReaderFS creates a reader that opens name in fsys, for example a
+// database embedded with go:embed. Close closes the fs.File.
+func NewMagicReaderFS(fsys fs.FS, name string, opts ...Option) *MagicReader {
+	r := NewMagicReaderWithPath(name, opts...)
+	r.fsys = fsys
+	return r
+}
+
+// NewMagicReaderFromReader creates a reader that parses the database from
+// src instead of a file, for example one embedded with go:embed. Open reads
+// the header from src and Close does nothing.
+func NewMagicReaderFromReader(src io.Reader, opts ...Option) *MagicReader {
+	r := NewMagicReaderWithPath("", opts...)
+	r.src = src
+	return r
 }
 
 func (r *MagicReader) Open() error {
-	f, err := os.Open(r.Filename)
-	if err != nil {
-		return err
+	src := r.src
+	if src == nil {
+		f, err := r.openFile()
+		if err != nil {
+			return err
+		}
+		r.file = f
+		src = f
+	}
+	r.atEOF = false
+	if m, ok := src.(*mappedFile); ok {
+		r.reader = m
+	} else {
+		r.reader = bufio.NewReader(r.wrapSource(src))
 	}
-	r.reader = bufio.NewReader(f)
-	r.file = f
 	return r.checkMagicHeader()
 }
 
+func (r *MagicReader) openFile() (io.ReadCloser, error) {
+	switch {
+	case r.fsys != nil:
+		return r.fsys.Open(r.Filename)
+	case r.useMmap:
+		return openMapped(r.Filename)
+	}
+	return os.Open(r.Filename)
+}
+
 func (r *MagicReader) Close() error {
+	if r.file == nil {
+		return nil
+	}
 	return r.file.Close()
 }
 
+func (r *MagicReader) wrapSource(src io.Reader) io.Reader {
+	if r.readTimeout > 0 {
+		return &timeoutReader{
+			reader:  src,
+			timeout: r.readTimeout,
+		}
+	}
+	return src
+}
+
 func (r *MagicReader) ReadSections() ([]*domain.Section, error) {
 	secs := make([]*domain.Section, 0, 10)
+	r.warnings = nil
+
+	budget := memoryBudget{limit: r.memoryBudget}
 
 	for {
-		buff, err := r.reader.ReadBytes('\n')
+		sec, err := r.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
 		if err != nil {
-			if !errors.Is(err, io.EOF) && !errors.Is(err, os.ErrClosed) {
-				log.Printf("Failed to read from file. err = %v", err)
-				return nil, err
-			}
+			return nil, err
+		}
+
+		if !budget.admit(sec) {
+			continue
+		}
+
+		secs = budget.charge(append(secs, sec))
+	}
+
+	if budget.truncated {
+		return secs, ErrBudgetExceeded
+	}
+
+	return secs, nil
+}
+
+// Next reads one section from the file, so a caller can stop as soon as it
+// finds what it needs. It returns io.EOF after the last section. The memory
+// budget only applies to ReadSections.
+func (r *MagicReader) Next() (*domain.Section, error) {
+	if r.reader == nil {
+		return nil, ErrNotOpened
+	}
+
+	buff, err := r.readLine()
+	if err != nil {
+		return nil, err
+	}
+
+	if buff[0] != '[' {
+		r.logger.Printf("Found content string, expected header.")
+		return nil, ErrHeaderCorrupted
+	}
+
+	sec, err := r.readHeader(buff)
+	if err != nil {
+		return nil, err
+	}
+
+	// Lines nested under a skipped line are skipped as well, as they would
+	// otherwise be attached to the previous sibling of the skipped line.
+	var (
+		skipping   bool
+		skipIndent uint
+	)
+
+	for {
+		if next, err := r.reader.Peek(1); err == nil && next[0] == '[' {
+			break
+		}
+
+		buff, err := r.readLine()
+		if errors.Is(err, io.EOF) {
 			break
 		}
+		if err != nil {
+			return nil, err
+		}
 
-		log.Printf("Read buffer %q", string(buff))
-		if buff[0] == '[' {
-			sec, err := r.readHeader(buff)
-			if err != nil {
+		con, err := r.readContent(buff)
+		if err != nil {
+			var skipped *skippedContentError
+			if !errors.As(err, &skipped) {
 				return nil, err
 			}
 
-			secs = append(secs, sec)
-		} else {
-			if len(secs) == 0 {
-				log.Printf("Found content string, expected header.")
-				return nil, ErrHeaderCorrupted
-			}
+			r.skipContent(sec, skipped.line, skipped.err)
+			skipping, skipIndent = true, skipped.indent
+			continue
+		}
 
-			con, err := r.readContent(buff)
-			if err != nil {
-				return nil, err
-			}
+		if skipping && con.Indent > skipIndent {
+			r.skipContent(sec, con.Raw, errParentSkipped)
+			continue
+		}
+		skipping = false
 
-			secs[len(secs)-1].Contents = append(secs[len(secs)-1].Contents, con)
+		if !r.rawContent {
+			con.Raw = nil
 		}
+		sec.Contents = append(sec.Contents, con)
 	}
 
-	return secs, nil
+	sec.Tree = domain.BuildTree(sec.Contents)
+
+	return sec, nil
+}
+
+func (r *MagicReader) skipContent(sec *domain.Section, line []byte, err error) {
+	r.logger.Printf("Skipping malformed content line. line = %q, err = %v", string(line), err)
+	r.warnings = append(r.warnings, Warning{
+		Filetype: sec.Filetype,
+		Line:     line,
+		Err:      err,
+	})
+}
+
+// readLine reads the next line of the file. A last line without a newline
+// is returned as if it had one. The end of the file is reported as io.EOF,
+// also when the file was closed under the reader.
+//
+// The line usually points into the buffer of the reader and is only valid
+// until the next read, so whatever is kept from it has to be copied.
+func (r *MagicReader) readLine() ([]byte, error) {
+	buff, err := r.reader.ReadSlice('\n')
+	if errors.Is(err, bufio.ErrBufferFull) {
+		long := append([]byte(nil), buff...)
+		for errors.Is(err, bufio.ErrBufferFull) {
+			buff, err = r.reader.ReadSlice('\n')
+			long = append(long, buff...)
+		}
+		buff = long
+	}
+	if errors.Is(err, io.EOF) && len(buff) > 0 {
+		r.atEOF = true
+		return append(buff[:len(buff):len(buff)], '\n'), nil
+	}
+	if err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, os.ErrClosed) {
+			return nil, io.EOF
+		}
+		r.logger.Printf("Failed to read from file. err = %v", err)
+		return nil, err
+	}
+
+	r.tracef("Read buffer %q", buff)
+	return buff, nil
+}
+
+// Warnings returns the content lines skipped since the last ReadSections
+// call. Lines are only skipped when the reader is created with WithLenientOffsets.
+func (r *MagicReader) Warnings() []Warning {
+	return r.warnings
+}
+
+// Header describes the file header read by Open. The shared-mime-info
+// header carries no version, so Version is empty for it.
+func (r *MagicReader) Header() (HeaderInfo, error) {
+	if r.header == nil {
+		return HeaderInfo{}, ErrNotOpened
+	}
+	return *r.header, nil
 }
 
 func (r *MagicReader) checkMagicHeader() error {
@@ -112,7 +357,10 @@ func (r *MagicReader) checkMagicHeader() error {
 
 	fileSign := make([]byte, len(sign))
 
-	if _, err := r.reader.Read(fileSign); err != nil {
+	if _, err := io.ReadFull(r.reader, fileSign); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return ErrFileIsNotMIMEMagic
+		}
 		return err
 	}
 
@@ -122,19 +370,28 @@ func (r *MagicReader) checkMagicHeader() error {
 		}
 	}
 
+	r.header = &HeaderInfo{
+		Format: FormatMIMEMagic,
+	}
+
 	return nil
 }
 
 func (r *MagicReader) readHeader(buff []byte) (*domain.Section, error) {
+	if len(buff) < 3 || !bytes.HasSuffix(buff, []byte("]\n")) {
+		r.logger.Printf("Failed to read section header. buff = %q", string(buff))
+		return nil, ErrHeaderCorrupted
+	}
+
 	priority, filetype, ok := bytes.Cut(buff[1:len(buff)-2], []byte{':'})
 	if !ok {
-		log.Printf("Failed to read section header. buff = %q", string(buff))
+		r.logger.Printf("Failed to read section header. buff = %q", string(buff))
 		return nil, ErrHeaderCorrupted
 	}
 
 	num, err := strconv.ParseUint(string(priority), 10, 32)
 	if err != nil {
-		log.Printf("Failed to parse section priority in header. err = %v", err)
+		r.logger.Printf("Failed to parse section priority in header. err = %v", err)
 		return nil, ErrHeaderCorrupted
 	}
 
@@ -146,96 +403,226 @@ func (r *MagicReader) readHeader(buff []byte) (*domain.Section, error) {
 }
 
 func (r *MagicReader) readContent(buff []byte) (*domain.Content, error) {
+	// In lenient mode the line is kept to report it if it is skipped. Next
+	// drops it again unless raw content was asked for.
+	var raw []byte
+	if r.rawContent || r.lenientOffsets {
+		raw = append(raw, buff...)
+	}
+
 	indent, buff, err := r.getUintToken(buff, '>')
 	if err != nil && !errors.Is(err, ErrTokenNotFound) {
 		return nil, err
 	}
+	if indent > r.maxIndent {
+		r.logger.Printf("Content indent exceeds limit. indent = %d, limit = %d", indent, r.maxIndent)
+		return nil, ErrContentCorrupted
+	}
 
-	offset, buff, err := r.getUintToken(buff, '=')
+	offset, offsetEnd, buff, offsetErr := r.getOffsetToken(buff)
+	if offsetErr != nil && (!r.lenientOffsets || !errors.Is(offsetErr, errMalformedToken)) {
+		return nil, offsetErr
+	}
+
+	sizeBytes, buff, err := r.readValue(buff, 2, &raw)
 	if err != nil {
 		return nil, err
 	}
+	size := int(binary.BigEndian.Uint16(sizeBytes))
+	r.tracef("Size of value in content: %d, size of buff: %d", size, len(buff))
+	if uint(size) > r.maxValueLen {
+		r.logger.Printf("Content value exceeds length limit. size = %d, limit = %d", size, r.maxValueLen)
+		return nil, ErrContentCorrupted
+	}
 
-	size := 0
-	if len(buff) >= 2 {
-		size = int(binary.BigEndian.Uint16(buff[:2]))
-		buff = buff[2:]
+	// The value and mask may point into the reader buffer, which the next
+	// read can overwrite, so each is copied out as soon as it is read. Both
+	// share one allocation.
+	valueMask := make([]byte, 2*size)
+	value, mask := valueMask[:size:size], valueMask[size:]
+
+	read, buff, err := r.readValue(buff, size, &raw)
+	if err != nil {
+		return nil, err
 	}
-	log.Printf("Size of value in content: %d, size of buff: %d", size, len(buff))
+	copy(value, read)
 
-	for len(buff) <= size {
-		tmpBuff, err := r.reader.ReadBytes('\n')
+	if buff[0] == '&' {
+		read, buff, err = r.readValue(buff[1:], size, &raw)
 		if err != nil {
-			log.Printf("Failed to append next line to buff. err = %v", err)
-			return nil, ErrContentCorrupted
+			return nil, err
+		}
+		copy(mask, read)
+	} else {
+		for i := range mask {
+			mask[i] = 0xff
 		}
+	}
 
-		log.Printf("Read additional buff = %q", string(tmpBuff))
-		buff = append(buff, tmpBuff...)
+	tail := buff[:len(buff)-1]
+	if r.trailingComments {
+		tail = stripComment(tail)
 	}
 
-	rangeLength, buff, err := r.getOptUintToken(buff[:len(buff)-1], '+')
+	wordSize, tail, err := r.getOptUintToken(tail, '~')
 	if err != nil {
 		return nil, err
 	}
 
-	wordSize, buff, err := r.getOptUintToken(buff, '~')
+	rangeLength, tail, err := r.getOptUintToken(tail, '+')
 	if err != nil {
 		return nil, err
 	}
 
-	value, mask, ok := bytes.Cut(buff, []byte{'&'})
-	if !ok {
-		mask = make([]byte, size)
-		for i := range mask {
-			mask[i] = 0xff
-		}
+	if len(tail) != 0 {
+		r.logger.Printf("Unexpected bytes after section content. tail = %q", string(tail))
+		return nil, ErrContentCorrupted
+	}
+
+	// Raw holds the source bytes, so the '\n' added to a last line
+	// without one is dropped again.
+	if r.atEOF && len(raw) > 0 {
+		raw = raw[:len(raw)-1]
+	}
+
+	if offsetErr != nil {
+		return nil, &skippedContentError{line: raw, indent: indent, err: offsetErr}
 	}
 
 	return &domain.Content{
 		Indent:      indent,
 		Offset:      offset,
+		OffsetEnd:   offsetEnd,
 		Value:       value,
 		Mask:        mask,
 		RangeLength: rangeLength,
 		WordSize:    wordSize,
+		Raw:         raw,
 	}, nil
 }
 
 func (r *MagicReader) getUintToken(buff []byte, del byte) (uint, []byte, error) {
 	tokenBytes, buff, ok := bytes.Cut(buff, []byte{del})
 	if !ok {
-		log.Printf("Failed to read section content  string. buff = %q", string(tokenBytes))
+		r.logger.Printf("Failed to read section content  string. buff = %q", string(tokenBytes))
 		return 0, nil, ErrContentCorrupted
 	}
+	token, err := r.parseUintToken(tokenBytes)
+	return token, buff, err
+}
+
+// getOffsetToken reads the offset before '=', which is either a single
+// number or a range "start:end" of starting offsets with end included.
+func (r *MagicReader) getOffsetToken(buff []byte) (uint, uint, []byte, error) {
+	tokenBytes, buff, ok := bytes.Cut(buff, []byte{'='})
+	if !ok {
+		r.logger.Printf("Failed to read section content offset. buff = %q", string(tokenBytes))
+		return 0, 0, nil, ErrContentCorrupted
+	}
+
+	startBytes, endBytes, isRange := bytes.Cut(tokenBytes, []byte{':'})
+	start, err := r.parseUintToken(startBytes)
+	if err != nil || !isRange {
+		return start, 0, buff, err
+	}
+
+	end, err := r.parseUintToken(endBytes)
+	if err != nil {
+		return 0, 0, buff, err
+	}
+	if end < start {
+		r.logger.Printf("Content offset range is empty. start = %d, end = %d", start, end)
+		return 0, 0, buff, errMalformedToken
+	}
+
+	return start, end, buff, nil
+}
+
+func (r *MagicReader) parseUintToken(tokenBytes []byte) (uint, error) {
 	if len(tokenBytes) == 0 {
-		return 0, buff, ErrTokenNotFound
+		return 0, ErrTokenNotFound
 	}
 	token, err := strconv.ParseUint(string(tokenBytes), 10, 32)
 	if err != nil {
-		log.Printf("Failed to parse section content indent string. err = %v", err)
-		return 0, nil, ErrContentCorrupted
+		r.logger.Printf("Failed to parse section content number. token = %q, err = %v", string(tokenBytes), err)
+		return 0, errMalformedToken
 	}
-	return uint(token), buff, nil
+	return uint(token), nil
 }
 
 func (r *MagicReader) getOptUintToken(buff []byte, del byte) (uint, []byte, error) {
-	var optVal uint = 1
-
-	buff, optBytes, ok := bytes.Cut(buff, []byte{del})
-	if ok {
-		if !unicode.IsDigit(rune(optBytes[0])) {
-			buff = append(buff, del)
-			buff = append(buff, optBytes...)
-		} else {
-			rangeLen, err := strconv.ParseUint(string(optBytes), 10, 32)
-			if err != nil {
-				log.Printf("Failed to parse section optional content string. del = %c, err = %v", del, err)
-				return 0, nil, ErrContentCorrupted
-			}
-			optVal = uint(rangeLen)
-		}
+	if len(buff) == 0 || buff[0] != del {
+		return 1, buff, nil
 	}
 
-	return optVal, buff, nil
+	end := 1
+	for end < len(buff) && buff[end] >= '0' && buff[end] <= '9' {
+		end++
+	}
+
+	optVal, err := strconv.ParseUint(string(buff[1:end]), 10, 32)
+	if err != nil {
+		r.logger.Printf("Failed to parse section optional content string. del = %c, err = %v", del, err)
+		return 0, nil, ErrContentCorrupted
+	}
+
+	return uint(optVal), buff[end:], nil
+}
+
+// readValue splits the first n bytes off buff. When the line read so far is
+// too short, because the value contains newlines, the missing bytes and the
+// rest of the line are read from the file.
+func (r *MagicReader) readValue(buff []byte, n int, raw *[]byte) ([]byte, []byte, error) {
+	if len(buff) > n {
+		return buff[:n], buff[n:], nil
+	}
+
+	// The appended '\n' is not part of the file, so a value that would
+	// take it is cut short.
+	if r.atEOF {
+		r.logger.Printf("Failed to read content value. size = %d, err = %v", n, io.ErrUnexpectedEOF)
+		return nil, nil, ErrContentCorrupted
+	}
+
+	value := make([]byte, n)
+	copy(value, buff)
+	if _, err := io.ReadFull(r.reader, value[len(buff):]); err != nil {
+		r.logger.Printf("Failed to read content value. size = %d, err = %v", n, err)
+		return nil, nil, contentReadError(err)
+	}
+
+	rest, err := r.reader.ReadBytes('\n')
+	if errors.Is(err, io.EOF) {
+		r.atEOF = true
+		rest, err = append(rest, '\n'), nil
+	}
+	if err != nil {
+		r.logger.Printf("Failed to read the rest of content line. err = %v", err)
+		return nil, nil, contentReadError(err)
+	}
+
+	r.tracef("Read value beyond line end = %q, rest = %q", value[len(buff):], rest)
+	if *raw != nil {
+		*raw = append(*raw, value[len(buff):]...)
+		*raw = append(*raw, rest...)
+	}
+	return value, rest, nil
+}
+
+// contentReadError reports a file that ends in the middle of a content line
+// as corrupted content and passes other read errors through.
+func contentReadError(err error) error {
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return ErrContentCorrupted
+	}
+	return err
+}
+
+// stripComment drops a '#' comment and the blanks before it from the part
+// of a content line that follows the value and mask.
+func stripComment(tail []byte) []byte {
+	if i := bytes.IndexByte(tail, '#'); i >= 0 {
+		tail = bytes.TrimRight(tail[:i], " \t")
+	}
+	return tail
 }
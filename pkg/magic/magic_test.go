@@ -0,0 +1,484 @@
+/*
+Copyright © 2023 Kovalev Pavel kovalev5690@gmail.com
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/package magic
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/Pavel7004/goMimeMagic/pkg/domain"
+)
+
+const magicHeader = "MIME-Magic\x00\n"
+
+// sources returns the same database behind a reader that returns it at
+// once and one that returns a single byte per Read, which makes every line
+// cross the bufio buffer boundary.
+func sources(data string) map[string]func() io.Reader {
+	return map[string]func() io.Reader{
+		"whole": func() io.Reader {
+			return strings.NewReader(data)
+		},
+		"one byte": func() io.Reader {
+			return iotest.OneByteReader(strings.NewReader(data))
+		},
+	}
+}
+
+func readSections(t *testing.T, src io.Reader, opts ...Option) ([]*domain.Section, error) {
+	t.Helper()
+
+	r := NewMagicReaderFromReader(src, opts...)
+	if err := r.Open(); err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	return r.ReadSections()
+}
+
+func TestReadContinuationValues(t *testing.T) {
+	tests := []struct {
+		name  string
+		line  string
+		value string
+		mask  string
+	}{
+		{
+			name:  "value with newline",
+			line:  ">0=\x00\x04AB\nD+3\n",
+			value: "AB\nD",
+			mask:  "\xff\xff\xff\xff",
+		},
+		{
+			name:  "mask with newline",
+			line:  ">0=\x00\x04ABCD&\xff\x0a\xff\xff~4+100\n",
+			value: "ABCD",
+			mask:  "\xff\n\xff\xff",
+		},
+		{
+			name:  "value and mask with newlines",
+			line:  ">0=\x00\x03\n\n\n&\n\xff\n\n",
+			value: "\n\n\n",
+			mask:  "\n\xff\n",
+		},
+	}
+
+	for _, tt := range tests {
+		for name, src := range sources(magicHeader + "[50:x/test]\n" + tt.line + "[40:x/next]\n>1=\x00\x01Z\n") {
+			t.Run(tt.name+"/"+name, func(t *testing.T) {
+				secs, err := readSections(t, src())
+				if err != nil {
+					t.Fatalf("ReadSections() error = %v", err)
+				}
+				if len(secs) != 2 || len(secs[0].Contents) != 1 {
+					t.Fatalf("ReadSections() = %v, want 2 sections with 1 content each", secs)
+				}
+
+				con := secs[0].Contents[0]
+				if !bytes.Equal(con.Value, []byte(tt.value)) {
+					t.Errorf("Value = %q, want %q", con.Value, tt.value)
+				}
+				if !bytes.Equal(con.Mask, []byte(tt.mask)) {
+					t.Errorf("Mask = %q, want %q", con.Mask, tt.mask)
+				}
+				if next := secs[1].Contents[0]; next.Offset != 1 || string(next.Value) != "Z" {
+					t.Errorf("next section content = %v, want >1=5a", next)
+				}
+			})
+		}
+	}
+}
+
+func TestLenientOffsetsSkipsNestedLines(t *testing.T) {
+	data := magicHeader + "[50:x/test]\n" +
+		">0=\x00\x02AB\n" +
+		">x=\x00\x02CD\n" +
+		"1>4=\x00\x01Z\n" +
+		"2>5=\x00\x01Y\n" +
+		">8=\x00\x01W\n" +
+		"1>9=\x00\x01V\n"
+
+	r := NewMagicReaderFromReader(strings.NewReader(data), WithLenientOffsets())
+	if err := r.Open(); err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	secs, err := r.ReadSections()
+	if err != nil {
+		t.Fatalf("ReadSections() error = %v", err)
+	}
+
+	var got []string
+	for _, con := range secs[0].Contents {
+		got = append(got, con.String())
+		if con.Raw != nil {
+			t.Errorf("Raw = %q, want nil without WithRawContent", con.Raw)
+		}
+	}
+	want := []string{">0=4142", ">8=57", "1>9=56"}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("Contents = %v, want %v", got, want)
+	}
+
+	warnings := r.Warnings()
+	if len(warnings) != 3 {
+		t.Fatalf("Warnings() = %v, want 3 warnings", warnings)
+	}
+	if !bytes.HasPrefix(warnings[0].Line, []byte(">x=")) || !errors.Is(warnings[0].Err, ErrContentCorrupted) {
+		t.Errorf("Warnings()[0] = %+v, want the malformed line", warnings[0])
+	}
+	for _, w := range warnings[1:] {
+		if !errors.Is(w.Err, errParentSkipped) {
+			t.Errorf("nested warning error = %v, want %v", w.Err, errParentSkipped)
+		}
+	}
+	if !bytes.HasPrefix(warnings[2].Line, []byte("2>5=")) {
+		t.Errorf("Warnings()[2].Line = %q, want the grandchild line", warnings[2].Line)
+	}
+}
+
+func TestTrailingComments(t *testing.T) {
+	tests := []struct {
+		name  string
+		line  string
+		value string
+		mask  string
+		word  uint
+		rng   uint
+	}{
+		{
+			name:  "hash in value",
+			line:  ">0=\x00\x03A#B # hand-edited\n",
+			value: "A#B",
+			mask:  "\xff\xff\xff",
+			word:  1,
+			rng:   1,
+		},
+		{
+			name:  "hash in mask",
+			line:  ">0=\x00\x02#\n&\xff#~2+4\t# comment\n",
+			value: "#\n",
+			mask:  "\xff#",
+			word:  2,
+			rng:   4,
+		},
+	}
+
+	for _, tt := range tests {
+		data := magicHeader + "[50:x/test]\n" + tt.line
+		for name, src := range sources(data) {
+			t.Run(tt.name+"/"+name, func(t *testing.T) {
+				secs, err := readSections(t, src(), WithTrailingComments())
+				if err != nil {
+					t.Fatalf("ReadSections() error = %v", err)
+				}
+
+				con := secs[0].Contents[0]
+				if !bytes.Equal(con.Value, []byte(tt.value)) {
+					t.Errorf("Value = %q, want %q", con.Value, tt.value)
+				}
+				if !bytes.Equal(con.Mask, []byte(tt.mask)) {
+					t.Errorf("Mask = %q, want %q", con.Mask, tt.mask)
+				}
+				if con.WordSize != tt.word || con.RangeLength != tt.rng {
+					t.Errorf("WordSize, RangeLength = %d, %d, want %d, %d", con.WordSize, con.RangeLength, tt.word, tt.rng)
+				}
+			})
+		}
+
+		t.Run(tt.name+"/strict", func(t *testing.T) {
+			_, err := readSections(t, strings.NewReader(data))
+			if !errors.Is(err, ErrContentCorrupted) {
+				t.Errorf("ReadSections() error = %v, want %v", err, ErrContentCorrupted)
+			}
+		})
+	}
+}
+
+func TestLastLineWithoutNewline(t *testing.T) {
+	data := magicHeader + "[50:x/first]\n>0=\x00\x01A\n" +
+		"[40:x/last]\n>0=\x00\x01B\n1>1=\x00\x01C\n>2=\x00\x02DE+4"
+
+	for name, src := range sources(data) {
+		t.Run(name, func(t *testing.T) {
+			secs, err := readSections(t, src())
+			if err != nil {
+				t.Fatalf("ReadSections() error = %v", err)
+			}
+			if len(secs) != 2 {
+				t.Fatalf("ReadSections() = %v, want 2 sections", secs)
+			}
+
+			var got []string
+			for _, con := range secs[1].Contents {
+				got = append(got, con.String())
+			}
+			want := []string{">0=42", "1>1=43", ">2=4445+4"}
+			if strings.Join(got, " ") != strings.Join(want, " ") {
+				t.Errorf("last section contents = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestLastLineCutShort(t *testing.T) {
+	// The newline added for the last line must not complete a value or
+	// mask that is missing its last byte.
+	for _, line := range []string{">0=\x00\x02A", ">0=\x00\x02AB&\xff", ">0=\x00\x02\n\n&\xff"} {
+		for name, src := range sources(magicHeader + "[50:x/test]\n" + line) {
+			_, err := readSections(t, src())
+			if !errors.Is(err, ErrContentCorrupted) {
+				t.Errorf("%s: %q: ReadSections() error = %v, want %v", name, line, err, ErrContentCorrupted)
+			}
+		}
+	}
+}
+
+func TestValueLengthLimit(t *testing.T) {
+	// The value is all there, so only the limit can reject it.
+	data := magicHeader + "[50:x/test]\n>0=\xff\xff" + strings.Repeat("A", 0xffff) + "\n"
+
+	_, err := readSections(t, strings.NewReader(data))
+	if !errors.Is(err, ErrContentCorrupted) {
+		t.Errorf("ReadSections() error = %v, want %v", err, ErrContentCorrupted)
+	}
+
+	secs, err := readSections(t, strings.NewReader(data), WithMaxValueLength(0xffff))
+	if err != nil {
+		t.Fatalf("ReadSections() with raised limit error = %v", err)
+	}
+	if n := len(secs[0].Contents[0].Value); n != 0xffff {
+		t.Errorf("len(Value) = %d, want %d", n, 0xffff)
+	}
+}
+
+func TestIndentLimit(t *testing.T) {
+	tests := []struct {
+		indent string
+		opts   []Option
+		err    error
+	}{
+		{indent: "100", err: ErrContentCorrupted},
+		{indent: "16"},
+		{indent: "100", opts: []Option{WithMaxIndent(100)}},
+	}
+
+	for _, tt := range tests {
+		data := magicHeader + "[50:x/test]\n>0=\x00\x01A\n" + tt.indent + ">1=\x00\x01B\n"
+
+		_, err := readSections(t, strings.NewReader(data), tt.opts...)
+		if !errors.Is(err, tt.err) {
+			t.Errorf("indent %s with %d options: ReadSections() error = %v, want %v", tt.indent, len(tt.opts), err, tt.err)
+		}
+	}
+}
+
+func TestHeaderReadOneByteAtATime(t *testing.T) {
+	tests := []struct {
+		data string
+		err  error
+	}{
+		{data: magicHeader + "[50:x/test]\n>0=\x00\x01A\n"},
+		{data: "MIME-Magic\x00", err: ErrFileIsNotMIMEMagic},
+		{data: "MIME-Mogic\x00\n", err: ErrFileIsNotMIMEMagic},
+	}
+
+	for _, tt := range tests {
+		r := NewMagicReaderFromReader(iotest.OneByteReader(strings.NewReader(tt.data)))
+		err := r.Open()
+		if !errors.Is(err, tt.err) {
+			t.Errorf("Open(%q) error = %v, want %v", tt.data, err, tt.err)
+		}
+		r.Close()
+	}
+}
+
+func TestReadLongAndTruncatedValues(t *testing.T) {
+	value := strings.Repeat("0123456789", 500)
+	data := magicHeader + "[50:x/test]\n>0=\x13\x88" + value + "&" + value + "\n"
+
+	for name, src := range sources(data) {
+		t.Run(name, func(t *testing.T) {
+			secs, err := readSections(t, src(), WithMaxValueLength(5000))
+			if err != nil {
+				t.Fatalf("ReadSections() error = %v", err)
+			}
+			con := secs[0].Contents[0]
+			if string(con.Value) != value || string(con.Mask) != value {
+				t.Errorf("Value and Mask differ from the %d byte input", len(value))
+			}
+		})
+	}
+
+	for _, cut := range []int{len(magicHeader) + 16, len(data) - len(value) - 1, len(data) - 2} {
+		for name, src := range sources(data[:cut]) {
+			_, err := readSections(t, src(), WithMaxValueLength(5000))
+			if !errors.Is(err, ErrContentCorrupted) {
+				t.Errorf("%s: cut at %d: ReadSections() error = %v, want %v", name, cut, err, ErrContentCorrupted)
+			}
+		}
+	}
+}
+
+func TestNext(t *testing.T) {
+	data := magicHeader +
+		"[80:x/a]\n>0=\x00\x01A\n" +
+		"[60:x/b]\n>0=\x00\x01B\n1>1=\x00\x01b\n" +
+		"[40:x/c]\n>0=\x00\x01C\n"
+
+	r := NewMagicReaderFromReader(strings.NewReader(data))
+	if _, err := r.Next(); !errors.Is(err, ErrNotOpened) {
+		t.Errorf("Next() before Open error = %v, want %v", err, ErrNotOpened)
+	}
+	if err := r.Open(); err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer r.Close()
+
+	var got []string
+	for {
+		sec, err := r.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Next() error = %v", err)
+		}
+		got = append(got, sec.String())
+	}
+	want := []string{"[80:x/a] 1 contents", "[60:x/b] 2 contents", "[40:x/c] 1 contents"}
+	if strings.Join(got, ", ") != strings.Join(want, ", ") {
+		t.Errorf("Next() returned %v, want %v", got, want)
+	}
+
+	if _, err := r.Next(); !errors.Is(err, io.EOF) {
+		t.Errorf("Next() after the end error = %v, want %v", err, io.EOF)
+	}
+}
+
+func TestTruncatedHeader(t *testing.T) {
+	for _, data := range []string{
+		magicHeader + "[\n",
+		magicHeader + "[",
+		magicHeader + "[50:x/a]\n>0=\x00\x01A\n[",
+		magicHeader + "[50:x/a",
+	} {
+		for name, src := range sources(data) {
+			_, err := readSections(t, src())
+			if !errors.Is(err, ErrHeaderCorrupted) {
+				t.Errorf("%s: %q: ReadSections() error = %v, want %v", name, data, err, ErrHeaderCorrupted)
+			}
+		}
+	}
+}
+
+func TestOffsetRange(t *testing.T) {
+	data := magicHeader +
+		"[60:x/range]\n>2:4=\x00\x02AB\n" +
+		"[50:x/single]\n>4:4=\x00\x02CD\n"
+
+	if errs := ValidateStrict([]byte(data)); len(errs) != 0 {
+		t.Errorf("ValidateStrict() = %v, want no errors", errs)
+	}
+
+	secs, err := readSections(t, strings.NewReader(data))
+	if err != nil {
+		t.Fatalf("ReadSections() error = %v", err)
+	}
+	if spans := [2]uint{secs[0].Contents[0].Span(), secs[1].Contents[0].Span()}; spans != [2]uint{3, 1} {
+		t.Errorf("Span() = %v, want [3 1]", spans)
+	}
+
+	db := NewDatabase(secs)
+	tests := []struct {
+		data string
+		want string
+	}{
+		{data: "..AB", want: "x/range"},
+		{data: "....AB", want: "x/range"},
+		{data: ".....AB", want: ""},
+		{data: "....CD", want: "x/single"},
+		{data: ".....CD", want: ""},
+	}
+	for _, tt := range tests {
+		if got, _ := db.Match([]byte(tt.data)); got != tt.want {
+			t.Errorf("Match(%q) = %q, want %q", tt.data, got, tt.want)
+		}
+	}
+
+	bad := magicHeader + "[50:x/bad]\n>4:3=\x00\x01A\n"
+	if _, err := readSections(t, strings.NewReader(bad)); !errors.Is(err, ErrContentCorrupted) {
+		t.Errorf("ReadSections() with end before start error = %v, want %v", err, ErrContentCorrupted)
+	}
+	if errs := ValidateStrict([]byte(bad)); len(errs) != 1 || errs[0].Offset != len(magicHeader)+14 {
+		t.Errorf("ValidateStrict() with end before start = %v, want one error at offset %d", errs, len(magicHeader)+14)
+	}
+}
+
+func TestHeader(t *testing.T) {
+	r := NewMagicReaderFromReader(strings.NewReader(magicHeader + "[50:x/test]\n>0=\x00\x01A\n"))
+	if _, err := r.Header(); !errors.Is(err, ErrNotOpened) {
+		t.Errorf("Header() before Open error = %v, want %v", err, ErrNotOpened)
+	}
+
+	if err := r.Open(); err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer r.Close()
+
+	header, err := r.Header()
+	if err != nil {
+		t.Fatalf("Header() error = %v", err)
+	}
+	if header.Format != FormatMIMEMagic || header.Version != nil {
+		t.Errorf("Header() = %+v, want {Format: %s}", header, FormatMIMEMagic)
+	}
+}
+
+func TestRawContent(t *testing.T) {
+	lines := []string{
+		">0=\x00\x02AB\n",
+		"1>4:8=\x00\x02\n\n&\xff\n~2+3\n",
+		">16=\x00\x01Z",
+	}
+	data := magicHeader + "[50:x/test]\n" + strings.Join(lines, "")
+
+	for name, src := range sources(data) {
+		t.Run(name, func(t *testing.T) {
+			secs, err := readSections(t, src(), WithRawContent())
+			if err != nil {
+				t.Fatalf("ReadSections() error = %v", err)
+			}
+			if len(secs[0].Contents) != len(lines) {
+				t.Fatalf("read %d contents, want %d", len(secs[0].Contents), len(lines))
+			}
+			for i, con := range secs[0].Contents {
+				if string(con.Raw) != lines[i] {
+					t.Errorf("Raw = %q, want %q", con.Raw, lines[i])
+				}
+			}
+		})
+	}
+}
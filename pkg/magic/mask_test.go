@@ -0,0 +1,49 @@
+/*
+Copyright © 2023 Kovalev Pavel kovalev5690@gmail.com
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/package magic
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestMergeMasks(t *testing.T) {
+	a := []byte{0xf0, 0x00, 0xff, 0x0f}
+	b := []byte{0x0f, 0x00, 0x0f, 0xf0}
+
+	mask, err := MergeMasks(a, b)
+	if err != nil {
+		t.Fatalf("MergeMasks() error = %v", err)
+	}
+	if want := []byte{0xff, 0x00, 0xff, 0xff}; !bytes.Equal(mask, want) {
+		t.Errorf("MergeMasks() = % x, want % x", mask, want)
+	}
+	if !bytes.Equal(a, []byte{0xf0, 0x00, 0xff, 0x0f}) {
+		t.Errorf("MergeMasks() changed its input to % x", a)
+	}
+}
+
+func TestMergeMasksLengthMismatch(t *testing.T) {
+	if _, err := MergeMasks([]byte{0xff}, []byte{0xff, 0xff}); !errors.Is(err, ErrMaskLengthMismatch) {
+		t.Errorf("MergeMasks() error = %v, want %v", err, ErrMaskLengthMismatch)
+	}
+}
@@ -0,0 +1,71 @@
+/*
+Copyright © 2023 Kovalev Pavel kovalev5690@gmail.com
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/package magic
+
+import (
+	"github.com/Pavel7004/goMimeMagic/pkg/domain"
+)
+
+// MergeByFiletype joins the sections that share a filetype into one, placed
+// where the first of them was. The merged section has the highest of their
+// priorities and the contents of all of them in order, so its top-level
+// rules are alternatives and any one matching classifies the data. Rules
+// taken from a lower-priority section are raised to the merged priority.
+// Merged sections hold copies of the contents; sections with a unique
+// filetype are returned as they are.
+func MergeByFiletype(secs []*domain.Section) []*domain.Section {
+	count := make(map[string]int, len(secs))
+	for _, sec := range secs {
+		count[sec.Filetype]++
+	}
+
+	merged := make(map[string]*domain.Section)
+	res := make([]*domain.Section, 0, len(count))
+	for _, sec := range secs {
+		if count[sec.Filetype] == 1 {
+			res = append(res, sec)
+			continue
+		}
+
+		m, ok := merged[sec.Filetype]
+		if !ok {
+			m = &domain.Section{
+				Filetype: sec.Filetype,
+				Priority: sec.Priority,
+			}
+			merged[sec.Filetype] = m
+			res = append(res, m)
+		}
+
+		if sec.Priority > m.Priority {
+			m.Priority = sec.Priority
+		}
+		for _, con := range sec.Contents {
+			m.Contents = append(m.Contents, copyContent(con))
+		}
+	}
+
+	for _, m := range merged {
+		m.Tree = domain.BuildTree(m.Contents)
+	}
+
+	return res
+}
@@ -0,0 +1,136 @@
+/*
+Copyright © 2023 Kovalev Pavel kovalev5690@gmail.com
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/package magic
+
+import (
+	"bytes"
+	"io"
+	"os"
+)
+
+// NewMagicReaderMmap creates a reader that memory-maps the magic file on
+// Open and parses lines straight from the mapping, without reading them
+// into a buffer first. Only values and masks are copied out, so the parsed
+// sections stay valid after Close unmaps the file. Systems without mmap
+// read the whole file into memory instead.
+func NewMagicReaderMmap(path string, opts ...Option) *MagicReader {
+	r := NewMagicReaderWithPath(path, opts...)
+	r.useMmap = true
+	return r
+}
+
+func openMapped(path string) (*mappedFile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	m := new(mappedFile)
+	if info.Size() == 0 {
+		return m, nil
+	}
+
+	m.data, err = mapFile(f, int(info.Size()))
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// mappedFile reads a mapped file and refuses reads once it is unmapped, as
+// touching the unmapped region would crash the program. It implements
+// lineReader, and the slices it returns point into the mapping. Their
+// capacity is cut to their length, so appending to them copies instead of
+// writing to the read-only mapping.
+type mappedFile struct {
+	data   []byte
+	off    int
+	closed bool
+}
+
+func (m *mappedFile) Read(p []byte) (int, error) {
+	if m.closed {
+		return 0, os.ErrClosed
+	}
+	if m.off >= len(m.data) {
+		return 0, io.EOF
+	}
+
+	n := copy(p, m.data[m.off:])
+	m.off += n
+	return n, nil
+}
+
+func (m *mappedFile) ReadSlice(delim byte) ([]byte, error) {
+	if m.closed {
+		return nil, os.ErrClosed
+	}
+	if m.off >= len(m.data) {
+		return nil, io.EOF
+	}
+
+	rest := m.data[m.off:]
+	i := bytes.IndexByte(rest, delim)
+	if i < 0 {
+		m.off = len(m.data)
+		return rest[:len(rest):len(rest)], io.EOF
+	}
+
+	m.off += i + 1
+	return rest[: i+1 : i+1], nil
+}
+
+func (m *mappedFile) ReadBytes(delim byte) ([]byte, error) {
+	line, err := m.ReadSlice(delim)
+	return append([]byte(nil), line...), err
+}
+
+func (m *mappedFile) Peek(n int) ([]byte, error) {
+	if m.closed {
+		return nil, os.ErrClosed
+	}
+
+	rest := m.data[m.off:]
+	if len(rest) < n {
+		return rest[:len(rest):len(rest)], io.EOF
+	}
+	return rest[:n:n], nil
+}
+
+func (m *mappedFile) Close() error {
+	if m.closed {
+		return nil
+	}
+	m.closed = true
+
+	if len(m.data) == 0 {
+		return nil
+	}
+	err := unmapFile(m.data)
+	m.data = nil
+	return err
+}
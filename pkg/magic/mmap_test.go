@@ -0,0 +1,79 @@
+/*
+Copyright © 2023 Kovalev Pavel kovalev5690@gmail.com
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/package magic
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Pavel7004/goMimeMagic/pkg/domain"
+)
+
+func TestMmapMatchesFileReads(t *testing.T) {
+	data := magicHeader +
+		"[80:x/a]\n>0=\x00\x04AB\nD&\xff\n\xff\xff~2+4\n1>8=\x00\x01Z\n" +
+		"[50:x/b]\n>4:8=\x00\x02CD\n>0=\x00\x01\n"
+	path := filepath.Join(t.TempDir(), "magic")
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	want, err := readFile(NewMagicReaderWithPath(path, WithRawContent()))
+	if err != nil {
+		t.Fatalf("file ReadSections() error = %v", err)
+	}
+
+	r := NewMagicReaderMmap(path, WithRawContent())
+	got, err := readFile(r)
+	if err != nil {
+		t.Fatalf("mmap ReadSections() error = %v", err)
+	}
+
+	if len(got) != len(want) {
+		t.Fatalf("mmap read %d sections, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if !got[i].Equal(want[i]) {
+			t.Errorf("section %d = %v, want %v", i, got[i], want[i])
+		}
+	}
+
+	// The sections are still readable after the mapping is gone, and the
+	// reader refuses to touch it.
+	if got[0].Contents[0].Value[0] != 'A' {
+		t.Errorf("Value after Close = %q", got[0].Contents[0].Value)
+	}
+	if _, err := r.Next(); !errors.Is(err, io.EOF) {
+		t.Errorf("Next() after Close error = %v, want %v", err, io.EOF)
+	}
+}
+
+func readFile(r *MagicReader) ([]*domain.Section, error) {
+	if err := r.Open(); err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	return r.ReadSections()
+}
@@ -0,0 +1,101 @@
+/*
+Copyright © 2023 Kovalev Pavel kovalev5690@gmail.com
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/package magic
+
+import (
+	"log"
+	"time"
+)
+
+type Option func(*MagicReader)
+
+// WithRawContent keeps the source bytes of every content line in
+// domain.Content.Raw.
+func WithRawContent() Option {
+	return func(r *MagicReader) {
+		r.rawContent = true
+	}
+}
+
+// WithMaxIndent limits the indent a content line may declare. Deeper lines
+// are rejected with ErrContentCorrupted. The default limit is 16.
+func WithMaxIndent(indent uint) Option {
+	return func(r *MagicReader) {
+		r.maxIndent = indent
+	}
+}
+
+// WithMaxValueLength limits the size a content line may declare for its
+// value, so a corrupted size can't make the reader allocate and read a lot.
+// Longer values are rejected with ErrContentCorrupted. The default limit
+// is 4096 bytes.
+func WithMaxValueLength(length uint) Option {
+	return func(r *MagicReader) {
+		r.maxValueLen = length
+	}
+}
+
+// WithLenientOffsets makes ReadSections skip content lines with a malformed
+// offset instead of failing, together with the lines nested under them.
+// Skipped lines are reported by Warnings.
+func WithLenientOffsets() Option {
+	return func(r *MagicReader) {
+		r.lenientOffsets = true
+	}
+}
+
+// WithReadTimeout makes a read from the magic file that takes longer than
+// timeout fail with ErrReadTimeout instead of blocking. This is meant for
+// FIFOs and slow mounts. There is no timeout by default.
+func WithReadTimeout(timeout time.Duration) Option {
+	return func(r *MagicReader) {
+		r.readTimeout = timeout
+	}
+}
+
+// WithMemoryBudget caps the Value and Mask bytes kept by ReadSections. Once
+// the cap is hit the lowest-priority sections are dropped, so the result
+// holds the highest-priority rules that fit. ReadSections then returns the
+// kept sections together with ErrBudgetExceeded. Dropped types can't be
+// detected at all, so a budget trades coverage for memory. A budget of 0
+// means no limit.
+func WithMemoryBudget(bytes int) Option {
+	return func(r *MagicReader) {
+		r.memoryBudget = bytes
+	}
+}
+
+// WithTrailingComments allows a '#' comment at the end of a content line,
+// as found in hand-edited databases. The value and mask are delimited by
+// their size prefix, so a '#' byte inside them is kept.
+func WithTrailingComments() Option {
+	return func(r *MagicReader) {
+		r.trailingComments = true
+	}
+}
+
+// WithLogger makes the reader report parse failures and skipped lines to
+// logger. Nothing is logged by default.
+func WithLogger(logger *log.Logger) Option {
+	return func(r *MagicReader) {
+		r.logger = logger
+	}
+}
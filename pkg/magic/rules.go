@@ -0,0 +1,95 @@
+/*
+Copyright © 2023 Kovalev Pavel kovalev5690@gmail.com
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/package magic
+
+import (
+	"sort"
+
+	"github.com/Pavel7004/goMimeMagic/pkg/domain"
+)
+
+// OffsetZeroRules returns the top-level contents that start at offset 0,
+// sorted by descending priority. Nested contents are left out, as they only
+// apply after their parent matched.
+func OffsetZeroRules(secs []*domain.Section) []domain.OwnedContent {
+	rules := make([]domain.OwnedContent, 0, len(secs))
+	for _, sec := range secs {
+		for _, con := range sec.Contents {
+			if con.Indent != 0 || con.Offset != 0 {
+				continue
+			}
+			rules = append(rules, domain.OwnedContent{
+				Filetype: sec.Filetype,
+				Priority: sec.Priority,
+				Content:  con,
+			})
+		}
+	}
+
+	sort.SliceStable(rules, func(i, j int) bool {
+		return rules[i].Priority > rules[j].Priority
+	})
+
+	return rules
+}
+
+// TypeOffsetBounds returns, per filetype, the smallest and largest offsets
+// at which its rules, nested ones included, are anchored. A range rule
+// counts up to the last offset it is tried at.
+func TypeOffsetBounds(secs []*domain.Section) map[string][2]uint {
+	bounds := make(map[string][2]uint, len(secs))
+	for _, sec := range secs {
+		for _, con := range sec.Contents {
+			last := con.Offset + con.Span() - 1
+
+			b, ok := bounds[sec.Filetype]
+			if !ok {
+				b = [2]uint{con.Offset, last}
+			}
+			if con.Offset < b[0] {
+				b[0] = con.Offset
+			}
+			if last > b[1] {
+				b[1] = last
+			}
+			bounds[sec.Filetype] = b
+		}
+	}
+	return bounds
+}
+
+// Priorities returns the distinct section priorities in ascending order.
+func Priorities(secs []*domain.Section) []uint {
+	seen := make(map[uint]bool)
+	prios := make([]uint, 0, 8)
+	for _, sec := range secs {
+		if !seen[sec.Priority] {
+			seen[sec.Priority] = true
+			prios = append(prios, sec.Priority)
+		}
+	}
+
+	sort.Slice(prios, func(i, j int) bool {
+		return prios[i] < prios[j]
+	})
+
+	return prios
+}
@@ -0,0 +1,97 @@
+/*
+Copyright © 2023 Kovalev Pavel kovalev5690@gmail.com
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/package magic
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Pavel7004/goMimeMagic/pkg/domain"
+)
+
+const mixedRules = magicHeader +
+	"[40:x/low]\n>0=\x00\x01L\n>4=\x00\x01M\n" +
+	"[80:x/high]\n>512=\x00\x01H\n>0=\x00\x01I\n1>0=\x00\x01J\n" +
+	"[60:x/mid]\n>0:8=\x00\x01N\n" +
+	"[80:x/far]\n>2=\x00\x01F\n" +
+	"[40:x/range]\n>0=\x00\x01R+4\n"
+
+func readMixedRules(t *testing.T) []*domain.Section {
+	t.Helper()
+
+	secs, err := readSections(t, strings.NewReader(mixedRules))
+	if err != nil {
+		t.Fatalf("ReadSections() error = %v", err)
+	}
+	return secs
+}
+
+func TestOffsetZeroRules(t *testing.T) {
+	var got []string
+	for _, rule := range OffsetZeroRules(readMixedRules(t)) {
+		got = append(got, rule.Filetype+" "+rule.Content.String())
+	}
+
+	want := []string{
+		"x/high >0=49",
+		"x/mid >0:8=4e",
+		"x/low >0=4c",
+		"x/range >0=52+4",
+	}
+	if strings.Join(got, ", ") != strings.Join(want, ", ") {
+		t.Errorf("OffsetZeroRules() = %v, want %v", got, want)
+	}
+}
+
+func TestTypeOffsetBounds(t *testing.T) {
+	bounds := TypeOffsetBounds(readMixedRules(t))
+
+	want := map[string][2]uint{
+		"x/low":   {0, 4},
+		"x/high":  {0, 512},
+		"x/mid":   {0, 8},
+		"x/far":   {2, 2},
+		"x/range": {0, 3},
+	}
+	if len(bounds) != len(want) {
+		t.Errorf("TypeOffsetBounds() = %v, want %v", bounds, want)
+	}
+	for filetype, b := range want {
+		if bounds[filetype] != b {
+			t.Errorf("TypeOffsetBounds()[%s] = %v, want %v", filetype, bounds[filetype], b)
+		}
+	}
+}
+
+func TestPriorities(t *testing.T) {
+	got := Priorities(readMixedRules(t))
+
+	want := []uint{40, 60, 80}
+	if len(got) != len(want) {
+		t.Fatalf("Priorities() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Priorities() = %v, want %v", got, want)
+			break
+		}
+	}
+}
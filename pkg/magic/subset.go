@@ -0,0 +1,74 @@
+/*
+Copyright © 2023 Kovalev Pavel kovalev5690@gmail.com
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/package magic
+
+import (
+	"github.com/Pavel7004/goMimeMagic/pkg/domain"
+)
+
+// Subset returns the sections of the types in keep together with every
+// type related to them: their aliases and, transitively, the types they
+// are subclasses of. aliases maps an alias to its canonical type and
+// subclasses maps a type to its parent types, as in the shared-mime-info
+// aliases and subclasses files. Section order is preserved.
+func Subset(secs []*domain.Section, keep []string, aliases map[string]string, subclasses map[string][]string) []*domain.Section {
+	aliasesOf := make(map[string][]string, len(aliases))
+	for alias, canonical := range aliases {
+		aliasesOf[canonical] = append(aliasesOf[canonical], alias)
+	}
+
+	wanted := make(map[string]bool, len(keep))
+	queue := make([]string, 0, len(keep))
+	add := func(filetype string) {
+		if !wanted[filetype] {
+			wanted[filetype] = true
+			queue = append(queue, filetype)
+		}
+	}
+
+	for _, filetype := range keep {
+		add(filetype)
+	}
+
+	for len(queue) > 0 {
+		filetype := queue[0]
+		queue = queue[1:]
+
+		if canonical, ok := aliases[filetype]; ok {
+			add(canonical)
+		}
+		for _, alias := range aliasesOf[filetype] {
+			add(alias)
+		}
+		for _, parent := range subclasses[filetype] {
+			add(parent)
+		}
+	}
+
+	res := make([]*domain.Section, 0, len(wanted))
+	for _, sec := range secs {
+		if wanted[sec.Filetype] {
+			res = append(res, sec)
+		}
+	}
+
+	return res
+}
@@ -0,0 +1,60 @@
+/*
+Copyright © 2023 Kovalev Pavel kovalev5690@gmail.com
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/package magic
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Pavel7004/goMimeMagic/pkg/domain"
+)
+
+func TestSubset(t *testing.T) {
+	var secs []*domain.Section
+	for _, filetype := range []string{
+		"application/xml",
+		"image/svg+xml",
+		"text/plain",
+		"application/x-svg",
+		"image/png",
+		"application/zip",
+	} {
+		secs = append(secs, &domain.Section{Filetype: filetype, Priority: 50})
+	}
+	aliases := map[string]string{
+		"application/x-svg": "image/svg+xml",
+		"application/x-zip": "application/zip",
+	}
+	subclasses := map[string][]string{
+		"image/svg+xml":   {"application/xml"},
+		"application/xml": {"text/plain"},
+	}
+
+	var got []string
+	for _, sec := range Subset(secs, []string{"image/svg+xml"}, aliases, subclasses) {
+		got = append(got, sec.Filetype)
+	}
+
+	want := "application/xml image/svg+xml text/plain application/x-svg"
+	if strings.Join(got, " ") != want {
+		t.Errorf("Subset() = %v, want %s", got, want)
+	}
+}
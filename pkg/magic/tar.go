@@ -0,0 +1,94 @@
+/*
+Copyright © 2023 Kovalev Pavel kovalev5690@gmail.com
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/package magic
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"path"
+)
+
+// NewMagicReaderFromTar reads the magic file stored as entryName in the tar
+// archive at tarPath. Gzip-compressed archives are detected automatically.
+// The returned reader is already open and its header is checked, so Open
+// must not be called on it.
+func NewMagicReaderFromTar(tarPath, entryName string, opts ...Option) (*MagicReader, error) {
+	f, err := os.Open(tarPath)
+	if err != nil {
+		return nil, err
+	}
+
+	r := NewMagicReaderWithPath(tarPath, opts...)
+
+	tr, err := r.newTarReader(r.wrapSource(f))
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			r.logger.Printf("Entry not found in tar archive. entry = %q", entryName)
+			f.Close()
+			return nil, ErrTarEntryNotFound
+		}
+		if err != nil {
+			r.logger.Printf("Failed to read tar archive. err = %v", err)
+			f.Close()
+			return nil, err
+		}
+
+		if hdr.Typeflag == tar.TypeReg && path.Clean(hdr.Name) == path.Clean(entryName) {
+			break
+		}
+	}
+
+	r.reader = bufio.NewReader(tr)
+	r.file = f
+
+	if err := r.checkMagicHeader(); err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return r, nil
+}
+
+func (r *MagicReader) newTarReader(src io.Reader) (*tar.Reader, error) {
+	br := bufio.NewReader(src)
+
+	sign, err := br.Peek(2)
+	if err == nil && sign[0] == 0x1f && sign[1] == 0x8b {
+		zr, err := gzip.NewReader(br)
+		if err != nil {
+			r.logger.Printf("Failed to open gzip stream. err = %v", err)
+			return nil, err
+		}
+		return tar.NewReader(zr), nil
+	}
+
+	return tar.NewReader(br), nil
+}
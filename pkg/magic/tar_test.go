@@ -0,0 +1,104 @@
+/*
+Copyright © 2023 Kovalev Pavel kovalev5690@gmail.com
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/package magic
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNewMagicReaderFromTar(t *testing.T) {
+	data, err := os.ReadFile(fixturePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := readFile(NewMagicReaderWithPath(fixturePath))
+	if err != nil {
+		t.Fatalf("ReadSections() error = %v", err)
+	}
+
+	var plain bytes.Buffer
+	tw := tar.NewWriter(&plain)
+	for _, entry := range []struct {
+		name string
+		data []byte
+	}{
+		{name: "usr/share/mime/README", data: []byte("not magic\n")},
+		{name: "usr/share/mime/magic", data: data},
+	} {
+		hdr := &tar.Header{Name: entry.name, Mode: 0o644, Size: int64(len(entry.data))}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write(entry.data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	var gzipped bytes.Buffer
+	zw := gzip.NewWriter(&gzipped)
+	if _, err := zw.Write(plain.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	for name, archive := range map[string][]byte{"mime.tar": plain.Bytes(), "mime.tar.gz": gzipped.Bytes()} {
+		tarPath := filepath.Join(dir, name)
+		if err := os.WriteFile(tarPath, archive, 0o644); err != nil {
+			t.Fatal(err)
+		}
+
+		r, err := NewMagicReaderFromTar(tarPath, "./usr/share/mime/magic")
+		if err != nil {
+			t.Fatalf("%s: NewMagicReaderFromTar() error = %v", name, err)
+		}
+		got, err := r.ReadSections()
+		r.Close()
+		if err != nil {
+			t.Fatalf("%s: ReadSections() error = %v", name, err)
+		}
+
+		if len(got) != len(want) {
+			t.Fatalf("%s: read %d sections, want %d", name, len(got), len(want))
+		}
+		for i := range want {
+			if !got[i].Equal(want[i]) {
+				t.Errorf("%s: section %d = %v, want %v", name, i, got[i], want[i])
+				break
+			}
+		}
+
+		if _, err := NewMagicReaderFromTar(tarPath, "usr/share/mime/globs"); !errors.Is(err, ErrTarEntryNotFound) {
+			t.Errorf("%s: NewMagicReaderFromTar() for a missing entry error = %v, want %v", name, err, ErrTarEntryNotFound)
+		}
+	}
+}
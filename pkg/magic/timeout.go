@@ -0,0 +1,65 @@
+/*
+Copyright © 2023 Kovalev Pavel kovalev5690@gmail.com
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/package magic
+
+import (
+	"context"
+	"io"
+	"time"
+)
+
+type readResult struct {
+	buff []byte
+	err  error
+}
+
+// timeoutReader gives up on a read that does not finish in time. The
+// abandoned read keeps running in its goroutine until the underlying file
+// is closed, so every later read fails with the same error.
+type timeoutReader struct {
+	reader  io.Reader
+	timeout time.Duration
+	err     error
+}
+
+func (t *timeoutReader) Read(p []byte) (int, error) {
+	if t.err != nil {
+		return 0, t.err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), t.timeout)
+	defer cancel()
+
+	done := make(chan readResult, 1)
+	go func() {
+		buff := make([]byte, len(p))
+		n, err := t.reader.Read(buff)
+		done <- readResult{buff: buff[:n], err: err}
+	}()
+
+	select {
+	case res := <-done:
+		return copy(p, res.buff), res.err
+	case <-ctx.Done():
+		t.err = ErrReadTimeout
+		return 0, t.err
+	}
+}
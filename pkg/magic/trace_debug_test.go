@@ -0,0 +1,51 @@
+//go:build magicdebug
+
+/*
+Copyright © 2023 Kovalev Pavel kovalev5690@gmail.com
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package magic
+
+import (
+	"bytes"
+	"log"
+	"strings"
+	"testing"
+)
+
+func TestTraceReachesLogger(t *testing.T) {
+	var out bytes.Buffer
+	logger := log.New(&out, "", log.Lshortfile)
+
+	data := magicHeader + "[50:x/test]\n>0=\x00\x02AB\n"
+	if _, err := readSections(t, strings.NewReader(data), WithLogger(logger)); err != nil {
+		t.Fatalf("ReadSections() error = %v", err)
+	}
+
+	trace := out.String()
+	if !strings.Contains(trace, "Read buffer") || !strings.Contains(trace, "Size of value in content: 2") {
+		t.Errorf("trace output = %q, want the parser trace lines", trace)
+	}
+	// The line is attributed to the parser, not to tracef.
+	if strings.Contains(trace, "trace_debug.go") || !strings.Contains(trace, "magic.go:") {
+		t.Errorf("trace output = %q, want lines from magic.go", trace)
+	}
+}
@@ -0,0 +1,289 @@
+/*
+Copyright © 2023 Kovalev Pavel kovalev5690@gmail.com
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/package magic
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"sort"
+)
+
+type ValidationError struct {
+	Offset int
+	Msg    string
+}
+
+func (e ValidationError) Error() string {
+	return fmt.Sprintf("offset %d: %s", e.Offset, e.Msg)
+}
+
+// ValidateStrict checks data against the shared-mime-info magic grammar and
+// returns every violation found. Unlike ReadSections it does not stop at
+// the first problem: a broken line is reported and skipped.
+func ValidateStrict(data []byte) []ValidationError {
+	v := &validator{data: data}
+	v.run()
+
+	sort.SliceStable(v.errs, func(i, j int) bool {
+		return v.errs[i].Offset < v.errs[j].Offset
+	})
+
+	return v.errs
+}
+
+type validator struct {
+	data []byte
+	pos  int
+	errs []ValidationError
+
+	inSection    bool
+	sectionStart int
+	rules        int
+	lastIndent   int
+}
+
+func (v *validator) run() {
+	sign := []byte("MIME-Magic\x00\n")
+	if !bytes.HasPrefix(v.data, sign) {
+		v.errorf(0, "missing MIME-Magic header")
+		return
+	}
+	v.pos = len(sign)
+
+	for v.pos < len(v.data) {
+		var ok bool
+		if v.data[v.pos] == '[' {
+			ok = v.section()
+		} else {
+			ok = v.content()
+		}
+		if !ok {
+			v.skipLine()
+		}
+	}
+
+	v.finishSection()
+}
+
+func (v *validator) section() bool {
+	v.finishSection()
+
+	start := v.pos
+	v.pos++
+
+	prio, ok := v.number()
+	if !ok {
+		v.errorf(v.pos, "expected section priority")
+		return false
+	}
+	if prio > 100 {
+		v.errorf(start+1, "priority %d is out of range 0-100", prio)
+	}
+
+	if !v.expect(':') {
+		return false
+	}
+
+	nl := bytes.IndexByte(v.data[v.pos:], '\n')
+	if nl < 0 {
+		v.errorf(start, "unterminated section header")
+		v.pos = len(v.data)
+		return true
+	}
+	nl += v.pos
+
+	if nl == v.pos || v.data[nl-1] != ']' {
+		v.errorf(nl, "section header must end with ']'")
+	} else if filetype := v.data[v.pos : nl-1]; bytes.Count(filetype, []byte{'/'}) != 1 || bytes.ContainsAny(filetype, "[] ") {
+		v.errorf(v.pos, "invalid media type %q", filetype)
+	}
+
+	v.pos = nl + 1
+	v.inSection = true
+	v.sectionStart = start
+	v.rules = 0
+	v.lastIndent = -1
+
+	return true
+}
+
+func (v *validator) content() bool {
+	start := v.pos
+	if !v.inSection {
+		v.errorf(start, "rule before any section header")
+		return false
+	}
+
+	indent := uint64(0)
+	if isDigit(v.data[v.pos]) {
+		indent, _ = v.number()
+	}
+	switch {
+	case v.lastIndent < 0 && indent > 0:
+		v.errorf(start, "first rule of a section has indent %d", indent)
+	case indent > uint64(v.lastIndent+1):
+		v.errorf(start, "indent %d follows indent %d", indent, v.lastIndent)
+	}
+
+	if !v.expect('>') {
+		return false
+	}
+	startOffset, ok := v.number()
+	if !ok {
+		v.errorf(v.pos, "expected start offset")
+		return false
+	}
+	if v.peek(':') {
+		v.pos++
+		at := v.pos
+		endOffset, ok := v.number()
+		if !ok {
+			v.errorf(at, "expected end offset")
+			return false
+		}
+		if endOffset < startOffset {
+			v.errorf(at, "end offset %d is before start offset %d", endOffset, startOffset)
+		}
+	}
+	if !v.expect('=') {
+		return false
+	}
+
+	if len(v.data)-v.pos < 2 {
+		v.errorf(v.pos, "truncated value length")
+		v.pos = len(v.data)
+		return true
+	}
+	size := int(binary.BigEndian.Uint16(v.data[v.pos:]))
+	v.pos += 2
+
+	if len(v.data)-v.pos < size {
+		v.errorf(v.pos, "value of %d bytes runs past end of file", size)
+		v.pos = len(v.data)
+		return true
+	}
+	v.pos += size
+
+	if v.peek('&') {
+		v.pos++
+		if len(v.data)-v.pos < size {
+			v.errorf(v.pos, "mask of %d bytes runs past end of file", size)
+			v.pos = len(v.data)
+			return true
+		}
+		v.pos += size
+	}
+
+	if v.peek('~') {
+		v.pos++
+		wordSize, ok := v.number()
+		switch {
+		case !ok:
+			v.errorf(v.pos, "expected word size")
+			return false
+		case wordSize != 1 && wordSize != 2 && wordSize != 4:
+			v.errorf(v.pos, "word size %d is not 1, 2 or 4", wordSize)
+		case size%int(wordSize) != 0:
+			v.errorf(v.pos, "value length %d is not a multiple of word size %d", size, wordSize)
+		}
+	}
+
+	if v.peek('+') {
+		v.pos++
+		rangeLength, ok := v.number()
+		if !ok {
+			v.errorf(v.pos, "expected range length")
+			return false
+		}
+		if rangeLength == 0 {
+			v.errorf(v.pos, "range length must be at least 1")
+		}
+	}
+
+	if !v.expect('\n') {
+		return false
+	}
+
+	v.rules++
+	v.lastIndent = int(indent)
+
+	return true
+}
+
+func (v *validator) finishSection() {
+	if v.inSection && v.rules == 0 {
+		v.errorf(v.sectionStart, "section has no rules")
+	}
+	v.inSection = false
+}
+
+func (v *validator) number() (uint64, bool) {
+	start := v.pos
+	var num uint64
+	for v.pos < len(v.data) && isDigit(v.data[v.pos]) {
+		if num <= 1<<32 {
+			num = num*10 + uint64(v.data[v.pos]-'0')
+		}
+		v.pos++
+	}
+	if num > 1<<32-1 {
+		v.errorf(start, "number is too large")
+	}
+	return num, v.pos > start
+}
+
+func (v *validator) peek(c byte) bool {
+	return v.pos < len(v.data) && v.data[v.pos] == c
+}
+
+func (v *validator) expect(c byte) bool {
+	if v.peek(c) {
+		v.pos++
+		return true
+	}
+	if v.pos >= len(v.data) {
+		v.errorf(v.pos, "expected %q, found end of file", c)
+	} else {
+		v.errorf(v.pos, "expected %q, found %q", c, v.data[v.pos:v.pos+1])
+	}
+	return false
+}
+
+func (v *validator) skipLine() {
+	nl := bytes.IndexByte(v.data[v.pos:], '\n')
+	if nl < 0 {
+		v.pos = len(v.data)
+		return
+	}
+	v.pos += nl + 1
+}
+
+func (v *validator) errorf(offset int, format string, args ...interface{}) {
+	v.errs = append(v.errs, ValidationError{
+		Offset: offset,
+		Msg:    fmt.Sprintf(format, args...),
+	})
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
@@ -0,0 +1,101 @@
+/*
+Copyright © 2023 Kovalev Pavel kovalev5690@gmail.com
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/package magic
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateStrict(t *testing.T) {
+	const (
+		sec  = "[50:x/test]\n"
+		rule = len(magicHeader) + len(sec)
+	)
+
+	tests := []struct {
+		name   string
+		data   string
+		offset int
+		msg    string
+	}{
+		{
+			name: "header",
+			data: "MIME-Magic\n" + sec + ">0=\x00\x01A\n",
+			msg:  "missing MIME-Magic header",
+		},
+		{
+			name:   "priority over 100",
+			data:   magicHeader + "[101:x/test]\n>0=\x00\x01A\n",
+			offset: len(magicHeader) + 1,
+			msg:    "priority 101",
+		},
+		{
+			name:   "indent jump",
+			data:   magicHeader + sec + ">0=\x00\x01A\n2>1=\x00\x01B\n",
+			offset: rule + 7,
+			msg:    "indent 2 follows indent 0",
+		},
+		{
+			name:   "mask past end of file",
+			data:   magicHeader + sec + ">0=\x00\x02AB&\xff",
+			offset: rule + 8,
+			msg:    "mask of 2 bytes",
+		},
+		{
+			name:   "bad word size",
+			data:   magicHeader + sec + ">0=\x00\x02AB~3\n",
+			offset: rule + 9,
+			msg:    "word size 3",
+		},
+		{
+			name:   "range length 0",
+			data:   magicHeader + sec + ">0=\x00\x01A+0\n",
+			offset: rule + 8,
+			msg:    "range length",
+		},
+		{
+			name:   "trailing garbage",
+			data:   magicHeader + sec + ">0=\x00\x01Ax\n",
+			offset: rule + 6,
+			msg:    "expected '\\n'",
+		},
+	}
+
+	// A broken rule can also leave its section without rules, so only the
+	// error the case is about is looked at.
+	for _, tt := range tests {
+		var found *ValidationError
+		errs := ValidateStrict([]byte(tt.data))
+		for i := range errs {
+			if strings.Contains(errs[i].Msg, tt.msg) {
+				found = &errs[i]
+				break
+			}
+		}
+
+		if found == nil {
+			t.Errorf("%s: ValidateStrict() = %v, want an error like %q", tt.name, errs, tt.msg)
+		} else if found.Offset != tt.offset {
+			t.Errorf("%s: error %q at offset %d, want %d", tt.name, found.Msg, found.Offset, tt.offset)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+/*
+Copyright © 2023 Kovalev Pavel kovalev5690@gmail.com
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/package magic
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/Pavel7004/goMimeMagic/pkg/domain"
+)
+
+var ErrNoDatabase = errors.New("No magic database found")
+
+// ReadXDGSections parses every mime/magic file under $XDG_DATA_HOME and
+// $XDG_DATA_DIRS and merges them the way shared-mime-info does: a filetype
+// defined in a more important directory replaces all its sections from
+// less important ones. The result is sorted by descending priority.
+func ReadXDGSections(opts ...Option) ([]*domain.Section, error) {
+	var (
+		merged []*domain.Section
+		seen   = make(map[string]bool)
+		found  bool
+	)
+
+	for _, dir := range xdgDataDirs() {
+		path := filepath.Join(dir, "mime", "magic")
+
+		secs, err := readSectionsFrom(path, opts...)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		found = true
+
+		defined := make(map[string]bool, len(secs))
+		for _, sec := range secs {
+			if seen[sec.Filetype] {
+				continue
+			}
+			defined[sec.Filetype] = true
+			merged = append(merged, sec)
+		}
+		for filetype := range defined {
+			seen[filetype] = true
+		}
+	}
+
+	if !found {
+		return nil, ErrNoDatabase
+	}
+
+	sort.SliceStable(merged, func(i, j int) bool {
+		return merged[i].Priority > merged[j].Priority
+	})
+
+	return merged, nil
+}
+
+func readSectionsFrom(path string, opts ...Option) ([]*domain.Section, error) {
+	r := NewMagicReaderWithPath(path, opts...)
+	if err := r.Open(); err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	return r.ReadSections()
+}
+
+// xdgDataDirs lists the data directories from the most to the least
+// important one, applying the defaults of the XDG base directory spec.
+func xdgDataDirs() []string {
+	dirs := make([]string, 0, 4)
+
+	home := os.Getenv("XDG_DATA_HOME")
+	if home == "" {
+		if userHome, err := os.UserHomeDir(); err == nil {
+			home = filepath.Join(userHome, ".local", "share")
+		}
+	}
+	if filepath.IsAbs(home) {
+		dirs = append(dirs, home)
+	}
+
+	dataDirs := os.Getenv("XDG_DATA_DIRS")
+	if dataDirs == "" {
+		dataDirs = "/usr/local/share:/usr/share"
+	}
+	for _, dir := range filepath.SplitList(dataDirs) {
+		if filepath.IsAbs(dir) {
+			dirs = append(dirs, dir)
+		}
+	}
+
+	return dirs
+}